@@ -0,0 +1,189 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog"
+
+	"github.com/onflow/flow-account-api/pkg/pg"
+	"github.com/onflow/flow-account-api/pkg/tracing"
+)
+
+// runConfig validates the configuration without connecting to the store or the access API.
+func runConfig(args []string, conf Config, logger zerolog.Logger) error {
+	if len(args) != 1 || args[0] != "check" {
+		return fmt.Errorf("unknown config action, must be check")
+	}
+
+	err := validateConfig(conf)
+	if err != nil {
+		return err
+	}
+
+	logger.Info().
+		Str("environment", conf.Environment).
+		Str("network", conf.NetworkType).
+		Str("storageBackend", conf.StorageBackend).
+		Int("port", conf.Port).
+		Int("grpcPort", conf.GRPCPort).
+		Str("internalAddress", conf.InternalAddress).
+		Msg("configuration is valid")
+
+	return nil
+}
+
+// checkCreatorConfig returns a description of every problem with the creator account and key,
+// which are only needed by the commands that create accounts.
+func checkCreatorConfig(conf Config) []string {
+	var problems []string
+
+	if conf.CreatorAddress == "" {
+		problems = append(problems, "creator address is required")
+	}
+
+	_, err := newCreatorSigner(conf)
+	if err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	return problems
+}
+
+// validateConfig returns an error describing every problem found by checkConfig, if there are any.
+func validateConfig(conf Config) error {
+	problems := checkConfig(conf)
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid configuration: %s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// checkConfig returns a description of every problem with the configuration
+// that would prevent the service from starting.
+func checkConfig(conf Config) []string {
+	problems := checkCreatorConfig(conf)
+
+	var err error
+
+	if conf.AccountLimit < 0 {
+		problems = append(problems, "account limit must not be negative")
+	}
+
+	if conf.AccountCountRefreshInterval <= 0 {
+		problems = append(problems, "account count refresh interval must be positive")
+	}
+
+	if conf.ShutdownTimeout <= 0 {
+		problems = append(problems, "shutdown timeout must be positive")
+	}
+
+	if conf.SealTimeout <= 0 {
+		problems = append(problems, "seal timeout must be positive")
+	}
+
+	if conf.Port == conf.GRPCPort {
+		problems = append(problems, fmt.Sprintf("HTTP and gRPC services cannot share port %d", conf.Port))
+	}
+
+	_, internalPort, err := net.SplitHostPort(conf.InternalAddress)
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("invalid internal address %q: %s", conf.InternalAddress, err))
+	} else if internalPort == strconv.Itoa(conf.Port) || internalPort == strconv.Itoa(conf.GRPCPort) {
+		problems = append(problems, fmt.Sprintf("internal server cannot share port %s with the HTTP or gRPC service", internalPort))
+	}
+
+	if conf.HTTPReadTimeout <= 0 ||
+		conf.HTTPReadHeaderTimeout <= 0 ||
+		conf.HTTPWriteTimeout <= 0 ||
+		conf.HTTPIdleTimeout <= 0 {
+		problems = append(problems, "HTTP timeouts must be positive")
+	}
+
+	if conf.HTTPMaxBodyBytes <= 0 {
+		problems = append(problems, "HTTP max body bytes must be positive")
+	}
+
+	if (conf.TLSCertPath == "") != (conf.TLSKeyPath == "") {
+		problems = append(problems, "TLS certificate and key paths must be set together")
+	} else if conf.TLSCertPath != "" {
+		_, err = tls.LoadX509KeyPair(conf.TLSCertPath, conf.TLSKeyPath)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("invalid TLS certificate: %s", err))
+		}
+	}
+
+	_, err = readWebhookSubscriptions(conf.WebhooksPath)
+	if err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	if conf.WebhookTimeout <= 0 || conf.WebhookPollInterval <= 0 {
+		problems = append(problems, "webhook timeout and poll interval must be positive")
+	}
+
+	if conf.WebhookMaxAttempts < 1 {
+		problems = append(problems, "webhook max attempts must be at least 1")
+	}
+
+	if conf.WebhookRetryDelay <= 0 || conf.WebhookMaxRetryDelay < conf.WebhookRetryDelay {
+		problems = append(problems, "webhook retry delay must be positive and no greater than the max retry delay")
+	}
+
+	switch conf.TracingExporter {
+	case tracing.ExporterNone, tracing.ExporterStdout, tracing.ExporterOTLP:
+	default:
+		problems = append(problems, fmt.Sprintf(
+			"unknown tracing exporter %q, must be one of %s, %s or %s",
+			conf.TracingExporter,
+			tracing.ExporterNone,
+			tracing.ExporterStdout,
+			tracing.ExporterOTLP,
+		))
+	}
+
+	if conf.TracingSampleRatio < 0 || conf.TracingSampleRatio > 1 {
+		problems = append(problems, "tracing sample ratio must be between 0 and 1")
+	}
+
+	switch conf.StorageBackend {
+	case storageBackendMemory:
+		if conf.MemorySnapshotInterval <= 0 {
+			problems = append(problems, "memory snapshot interval must be positive")
+		}
+	case storageBackendPostgres:
+		if conf.PostgreSQLPoolSize <= 0 {
+			problems = append(problems, "Postgres pool size must be positive")
+		}
+
+		switch conf.PostgreSQLMigrationMode {
+		case pg.MigrationModeAuto, pg.MigrationModeVerify, pg.MigrationModeOff:
+		default:
+			problems = append(problems, fmt.Sprintf(
+				"unknown Postgres migration mode %q, must be one of %s, %s or %s",
+				conf.PostgreSQLMigrationMode,
+				pg.MigrationModeAuto,
+				pg.MigrationModeVerify,
+				pg.MigrationModeOff,
+			))
+		}
+	case storageBackendBolt:
+		if conf.BoltPath == "" {
+			problems = append(problems, "Bolt path must not be empty")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf(
+			"unknown storage backend %q, must be one of %s, %s or %s",
+			conf.StorageBackend,
+			storageBackendMemory,
+			storageBackendPostgres,
+			storageBackendBolt,
+		))
+	}
+
+	return problems
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog"
+
+	"github.com/onflow/flow-account-api/model"
+	"github.com/onflow/flow-account-api/wallet"
+)
+
+// cliClientID identifies accounts created from the command line.
+const cliClientID = "account-api-cli"
+
+// runCreateAccount creates a single account on chain, stores it and prints it as JSON.
+func runCreateAccount(args []string, conf Config, logger zerolog.Logger) (err error) {
+	flags := flag.NewFlagSet("create-account", flag.ExitOnError)
+	publicKey := flags.String("public-key", "", "public key of the new account")
+	sigAlgo := flags.String("signature-algorithm", "ECDSA_P256", "signature algorithm of the public key")
+	hashAlgo := flags.String("hash-algorithm", "SHA3_256", "hash algorithm of the public key")
+	clientID := flags.String("client-id", cliClientID, "client ID recorded with the account")
+
+	err = flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if *publicKey == "" {
+		return fmt.Errorf("-public-key is required")
+	}
+
+	if problems := checkCreatorConfig(conf); len(problems) > 0 {
+		return fmt.Errorf("invalid configuration: %s", strings.Join(problems, "; "))
+	}
+
+	store, closeStore, err := openStore(conf, logger)
+	if err != nil {
+		return fmt.Errorf("failed to open store: %w", err)
+	}
+	defer closeOnReturn(closeStore, &err)
+
+	webhooks, err := readWebhookSubscriptions(conf.WebhooksPath)
+	if err != nil {
+		return err
+	}
+
+	service, err := newService(conf, logger, store, wallet.NewAccountsCollector(conf.NetworkType), webhooks)
+	if err != nil {
+		return err
+	}
+
+	account, err := service.CreateAccount(
+		context.Background(),
+		*publicKey,
+		*sigAlgo,
+		*hashAlgo,
+		model.ClientMetadata{ID: *clientID, Admin: true},
+	)
+	if err != nil {
+		return err
+	}
+
+	return printJSON(account)
+}
+
+// closeOnReturn closes a store or file when a command returns,
+// replacing the command's result with the close error if the command succeeded.
+func closeOnReturn(close func() error, err *error) {
+	closeErr := close()
+	if *err == nil {
+		*err = closeErr
+	}
+}
+
+func printJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(v)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/rs/zerolog"
+
+	"github.com/onflow/flow-account-api/storage"
+)
+
+// exportPageSize is the number of accounts read from the store at a time.
+const exportPageSize = 1000
+
+// runExport writes every account in the configured store to a file or to standard output.
+func runExport(args []string, conf Config, logger zerolog.Logger) (err error) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	format := flags.String("format", formatNDJSON, "output format, ndjson or csv")
+	file := flags.String("file", "-", "output file, or - for standard output")
+
+	err = flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	store, closeStore, err := openStore(conf, logger)
+	if err != nil {
+		return fmt.Errorf("failed to open store: %w", err)
+	}
+	defer closeOnReturn(closeStore, &err)
+
+	var out io.Writer = os.Stdout
+
+	if *file != "-" {
+		f, err := os.Create(*file)
+		if err != nil {
+			return err
+		}
+		defer closeOnReturn(f.Close, &err)
+
+		out = f
+	}
+
+	writer, err := newRecordWriter(*format, out)
+	if err != nil {
+		return err
+	}
+
+	count, err := exportAccounts(context.Background(), store, writer)
+	if err != nil {
+		return err
+	}
+
+	err = writer.Flush()
+	if err != nil {
+		return err
+	}
+
+	logger.Info().Int("accounts", count).Msg("export complete")
+
+	return nil
+}
+
+// exportAccounts pages through all accounts in creation order and returns the number written.
+func exportAccounts(ctx context.Context, store storage.Store, writer recordWriter) (int, error) {
+	var (
+		cursor storage.Cursor
+		count  int
+	)
+
+	for {
+		page, err := store.ListAccounts(ctx, storage.AccountFilter{}, cursor, exportPageSize)
+		if err != nil {
+			return count, fmt.Errorf("failed to list accounts: %w", err)
+		}
+
+		for _, account := range page.Accounts {
+			err := writer.Write(account)
+			if err != nil {
+				return count, fmt.Errorf("failed to write account %s: %w", account.Address, err)
+			}
+
+			count++
+		}
+
+		if page.NextCursor == "" {
+			return count, nil
+		}
+
+		cursor, err = storage.DecodeCursor(page.NextCursor)
+		if err != nil {
+			return count, err
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/rs/zerolog"
+
+	"github.com/onflow/flow-account-api/model"
+	"github.com/onflow/flow-account-api/storage"
+)
+
+// importSummary counts the outcome of each account read during an import.
+type importSummary struct {
+	Imported   int
+	Existing   int // already stored with the same keys
+	Duplicates int // repeated within the input
+	Conflicts  int // stored or repeated with different keys
+}
+
+// runImport reads accounts from a file or from standard input and inserts them into the configured store.
+//
+// Accounts that already exist with the same keys are skipped, so an import can safely be re-run.
+// Accounts that conflict with a stored account or with an earlier record are reported and skipped,
+// and the command fails if there were any conflicts.
+func runImport(args []string, conf Config, logger zerolog.Logger) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	format := flags.String("format", formatNDJSON, "input format, ndjson or csv")
+	file := flags.String("file", "-", "input file, or - for standard input")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	var in io.Reader = os.Stdin
+
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		in = f
+	}
+
+	reader, err := newRecordReader(*format, in)
+	if err != nil {
+		return err
+	}
+
+	store, closeStore, err := openStore(conf, logger)
+	if err != nil {
+		return fmt.Errorf("failed to open store: %w", err)
+	}
+
+	summary, err := importAccounts(context.Background(), store, reader, logger)
+
+	closeErr := closeStore()
+	if err == nil {
+		err = closeErr
+	}
+
+	logger.Info().
+		Int("imported", summary.Imported).
+		Int("existing", summary.Existing).
+		Int("duplicates", summary.Duplicates).
+		Int("conflicts", summary.Conflicts).
+		Msg("import complete")
+
+	if err != nil {
+		return err
+	}
+
+	if summary.Conflicts > 0 {
+		return fmt.Errorf("%d conflicting accounts were not imported", summary.Conflicts)
+	}
+
+	return nil
+}
+
+func importAccounts(
+	ctx context.Context,
+	store storage.Store,
+	reader recordReader,
+	logger zerolog.Logger,
+) (importSummary, error) {
+	var summary importSummary
+
+	// accounts already read from the input, by normalized address
+	seen := make(map[string]*model.Account)
+
+	for {
+		account, err := reader.Read()
+		if err == io.EOF {
+			return summary, nil
+		}
+		if err != nil {
+			return summary, fmt.Errorf("failed to read account: %w", err)
+		}
+
+		address, err := storage.NormalizeAddress(account.Address)
+		if err != nil {
+			return summary, fmt.Errorf("invalid account address %q: %w", account.Address, err)
+		}
+
+		account.Address = address
+
+		if previous, ok := seen[address]; ok {
+			if sameAccount(previous, account) {
+				summary.Duplicates++
+			} else {
+				summary.Conflicts++
+				logger.Warn().Str("address", address).Msg("account appears more than once with different keys")
+			}
+
+			continue
+		}
+
+		seen[address] = account
+
+		var existing model.Account
+
+		err = store.GetAccountByAddress(ctx, address, &existing)
+		if err == nil {
+			if sameAccount(&existing, account) {
+				summary.Existing++
+			} else {
+				summary.Conflicts++
+				logger.Warn().Str("address", address).Msg("account already exists with different keys")
+			}
+
+			continue
+		}
+		if !errors.Is(err, storage.ErrNotFound) {
+			return summary, fmt.Errorf("failed to look up account %s: %w", address, err)
+		}
+
+		err = store.InsertAccount(ctx, account, newImportEvent(account))
+		if err != nil {
+			if errors.Is(err, storage.ErrExists) {
+				summary.Conflicts++
+				logger.Warn().Str("address", address).Msg("account conflicts with a stored account")
+
+				continue
+			}
+
+			return summary, fmt.Errorf("failed to insert account %s: %w", address, err)
+		}
+
+		summary.Imported++
+	}
+}
+
+// newImportEvent returns the audit event recording an imported account,
+// which is stored in the same transaction as the account.
+func newImportEvent(account *model.Account) *model.AuditEvent {
+	publicKeys := make([]string, len(account.PublicKeys))
+	for i, publicKey := range account.PublicKeys {
+		publicKeys[i] = publicKey.PublicKey
+	}
+
+	return &model.AuditEvent{
+		Type:            model.AuditEventAccountImported,
+		Actor:           model.AuditActorAdmin,
+		Address:         account.Address,
+		PublicKeys:      publicKeys,
+		TransactionID:   account.CreationTransactionID,
+		ClaimedClientID: cliClientID,
+	}
+}
+
+// sameAccount reports whether two accounts with the same address have the same keys and creation transaction.
+func sameAccount(a, b *model.Account) bool {
+	if a.CreationTransactionID != b.CreationTransactionID || len(a.PublicKeys) != len(b.PublicKeys) {
+		return false
+	}
+
+	keys := make(map[model.AccountPublicKey]bool, len(a.PublicKeys))
+	for _, publicKey := range a.PublicKeys {
+		keys[model.AccountPublicKey{
+			PublicKey: publicKey.PublicKey,
+			SigAlgo:   publicKey.SigAlgo,
+			HashAlgo:  publicKey.HashAlgo,
+		}] = true
+	}
+
+	for _, publicKey := range b.PublicKeys {
+		if !keys[model.AccountPublicKey{
+			PublicKey: publicKey.PublicKey,
+			SigAlgo:   publicKey.SigAlgo,
+			HashAlgo:  publicKey.HashAlgo,
+		}] {
+			return false
+		}
+	}
+
+	return true
+}
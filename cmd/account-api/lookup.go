@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/rs/zerolog"
+
+	"github.com/onflow/flow-account-api/model"
+	"github.com/onflow/flow-account-api/wallet"
+)
+
+// runLookup prints the accounts associated with a public key, or the account at an address, as JSON.
+func runLookup(args []string, conf Config, logger zerolog.Logger) (err error) {
+	flags := flag.NewFlagSet("lookup", flag.ExitOnError)
+	publicKey := flags.String("public-key", "", "public key to look up accounts by")
+	address := flags.String("address", "", "address of the account to look up")
+
+	err = flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if (*publicKey == "") == (*address == "") {
+		return fmt.Errorf("exactly one of -public-key or -address is required")
+	}
+
+	store, closeStore, err := openStore(conf, logger)
+	if err != nil {
+		return fmt.Errorf("failed to open store: %w", err)
+	}
+	defer closeOnReturn(closeStore, &err)
+
+	ctx := context.Background()
+
+	if *address != "" {
+		var account model.Account
+
+		err := store.GetAccountByAddress(ctx, *address, &account)
+		if err != nil {
+			return err
+		}
+
+		return printJSON(&account)
+	}
+
+	normalizedPublicKey, err := wallet.NormalizePublicKey(*publicKey)
+	if err != nil {
+		return err
+	}
+
+	accounts, err := store.GetAccountsByPublicKey(ctx, normalizedPublicKey)
+	if err != nil {
+		return err
+	}
+
+	return printJSON(accounts)
+}
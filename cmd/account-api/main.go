@@ -1,15 +1,15 @@
 package main
 
 import (
+	"fmt"
+	"io"
 	"os"
 	"time"
 
-	"github.com/onflow/flow-go-sdk"
-	"github.com/onflow/flow-go-sdk/crypto"
-	"github.com/psiemens/graceland"
 	"github.com/psiemens/sconfig"
 	"github.com/rs/zerolog"
 
+	"github.com/onflow/flow-account-api/pkg/logging"
 	"github.com/onflow/flow-account-api/pkg/pg"
 	"github.com/onflow/flow-account-api/storage/postgres"
 	"github.com/onflow/flow-account-api/wallet"
@@ -19,88 +19,170 @@ type Config struct {
 	AppName     string `default:"flow-account-api"`
 	Environment string `required:"true"`
 	Port        int    `default:"8080"`
+	GRPCPort    int    `default:"9090"`
 
-	CreatorAddress     string `required:"true"`
+	InternalAddress string `default:":8082"` // health checks, metrics, profiling and admin routes
+
+	HTTPReadTimeout       time.Duration `default:"30s"`
+	HTTPReadHeaderTimeout time.Duration `default:"10s"`
+	HTTPWriteTimeout      time.Duration `default:"2m"` // must cover the time to seal an account creation
+	HTTPIdleTimeout       time.Duration `default:"2m"`
+	HTTPMaxBodyBytes      int64         `default:"65536"`
+
+	TLSCertPath string // the API is served over plain HTTP if empty
+	TLSKeyPath  string
+
+	LogLevel  string `default:"info"`
+	LogFormat string `default:"json"` // json or console
+
+	// the creator key is only needed by the commands that create accounts, serve and create-account,
+	// which check it with checkCreatorConfig
+	CreatorAddress     string
 	CreatorPrivateKey  string
-	CreatorKeyIndex    int    `default:"0"`
-	CreatorKeySigAlgo  string `required:"true"`
-	CreatorKeyHashAlgo string `required:"true"`
+	CreatorKeyIndex    int `default:"0"`
+	CreatorKeySigAlgo  string
+	CreatorKeyHashAlgo string
 
 	NetworkType   string `default:"emulator"`
 	AccessAPIHost string
 
-	AccountLimit                       int  `default:"0"` // Zero is assumed to mean no limit
+	AccountLimit        int  `default:"0"` // Zero is assumed to mean no limit
+	SingleAccountLookup bool `default:"false"`
+
+	AccountCountRefreshInterval time.Duration `default:"30s"` // how often the account count metric is updated
+
+	ShutdownTimeout time.Duration `default:"30s"` // how long to wait for account creations and gRPC calls in flight on shutdown
+	SealTimeout     time.Duration `default:"2m"`  // how long an account creation waits for its transaction to be sealed
+
+	StorageBackend string `default:"postgres"` // memory, postgres or bolt
+	BoltPath       string `default:"account-api.db"`
+
+	MemorySnapshotPath     string        // snapshots of the memory store are disabled if empty
+	MemorySnapshotInterval time.Duration `default:"1m"`
+
+	WebhooksPath         string        // JSON file of webhook subscriptions; empty disables webhooks
+	WebhookTimeout       time.Duration `default:"10s"`
+	WebhookMaxAttempts   int           `default:"10"`
+	WebhookRetryDelay    time.Duration `default:"5s"`
+	WebhookMaxRetryDelay time.Duration `default:"1h"`
+	WebhookPollInterval  time.Duration `default:"1s"`
+
+	TracingExporter     string  `default:"none"` // none, stdout or otlp
+	TracingOTLPEndpoint string  `default:"localhost:55680"`
+	TracingSampleRatio  float64 `default:"1"`
 
 	PostgreSQLHost              string        `default:"localhost"`
 	PostgreSQLPort              uint16        `default:"5432"`
 	PostgreSQLUsername          string        `default:"postgres"`
 	PostgreSQLPassword          string        `required:"false"`
-	PostgreSQLDatabase          string        `required:"true"`
+	PostgreSQLDatabase          string        `default:"account-api"`
 	PostgreSQLSSL               bool          `default:"true"`
 	PostgreSQLLogQueries        bool          `default:"false"`
 	PostgreSQLSetLogger         bool          `default:"false"`
 	PostgreSQLRetryNumTimes     uint16        `default:"30"`
 	PostgreSQLRetrySleepTime    time.Duration `default:"1s"`
-	PostgreSQLMigrationPath     string        `default:"/data/migrations"`
-	PostgreSQLPoolSize          int           `required:"true"`
-	PostgresLoggerPrefix        string        `required:"true"`
-	PostgresPrometheusSubSystem string        `required:"true"`
+	PostgreSQLMigrationMode     string        `default:"auto"` // auto, verify or off
+	PostgreSQLPoolSize          int           `default:"10"`
+	PostgreSQLQueryTimeout      time.Duration `default:"5s"`
+	PostgresLoggerPrefix        string        `default:"account_api_dal"`
+	PostgresPrometheusSubSystem string        `default:"account_api_dal"`
 }
 
 var conf Config
 
 const envPrefix = "FLOW"
 
+// command is a subcommand of the account-api binary.
+type command struct {
+	name        string
+	usage       string
+	description string
+	run         func(args []string, conf Config, logger zerolog.Logger) error
+}
+
+var commands = []command{
+	{"serve", "serve", "run the HTTP and gRPC services (default)", runServe},
+	{"migrate", "migrate up|down|version", "manage the Postgres schema", runMigrate},
+	{"create-account", "create-account -public-key KEY [flags]", "create and store a single account", runCreateAccount},
+	{"lookup", "lookup -public-key KEY | -address ADDRESS", "look up stored accounts", runLookup},
+	{"config", "config check", "validate the configuration", runConfig},
+	{"export", "export [-format ndjson|csv] [-file PATH]", "write every stored account to a file", runExport},
+	{"import", "import [-format ndjson|csv] [-file PATH]", "read accounts from a file into the store", runImport},
+}
+
 func main() {
+	os.Exit(run())
+}
+
+// run runs the command named by the arguments and returns the exit code of the process.
+//
+// Errors are returned rather than logged as fatal so that every deferred close has run before the process exits.
+func run() int {
+	// serve is the default when no command is given
+	name := "serve"
+	args := os.Args[1:]
+
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+
+	if name == "help" || name == "-h" || name == "-help" || name == "--help" {
+		printUsage(os.Stdout)
+		return 0
+	}
+
+	cmd, ok := findCommand(name)
+	if !ok {
+		printUsage(os.Stderr)
+		return 2
+	}
+
+	logger := zerolog.New(os.Stderr).With().Timestamp().Logger()
+
+	// every command shares the same configuration
 	err := sconfig.New(&conf).
 		FromEnvironment(envPrefix).
 		Parse()
 	if err != nil {
-		panic(err)
+		logger.Error().Err(err).Msg("invalid configuration")
+		return 1
 	}
 
-	creatorAddress := flow.HexToAddress(conf.CreatorAddress)
-
-	creatorKeySigAlgo := crypto.StringToSignatureAlgorithm(conf.CreatorKeySigAlgo)
-	creatorKeyHashAlgo := crypto.StringToHashAlgorithm(conf.CreatorKeyHashAlgo)
-
-	creatorPrivateKey, err := crypto.DecodePrivateKeyHex(creatorKeySigAlgo, conf.CreatorPrivateKey)
+	configured, err := logging.New(os.Stderr, conf.LogLevel, conf.LogFormat)
 	if err != nil {
-		panic(err)
+		logger.Error().Err(err).Msg("invalid logging configuration")
+		return 1
 	}
 
-	creatorSigner := crypto.NewInMemorySigner(creatorPrivateKey, creatorKeyHashAlgo)
+	logger = configured
 
-	accounts, err := wallet.NewAccounts(
-		conf.AccessAPIHost,
-		creatorAddress,
-		conf.CreatorKeyIndex,
-		creatorSigner,
-		conf.AccountLimit,
-	)
+	err = cmd.run(args, conf, logger)
 	if err != nil {
-		panic(err)
+		logger.Error().Err(err).Msgf("%s failed", cmd.name)
+		return 1
 	}
 
-	logger := zerolog.New(os.Stderr)
-
-	// store := memory.NewStore()
+	return 0
+}
 
-	store, err := postgres.NewStore(getPostgresConfig(conf, logger), conf.Environment, logger)
-	if err != nil {
-		logger.Fatal().Err(err).Msg("failed to initial Postgres database")
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
 	}
 
-	service := wallet.NewService(conf.Port, logger, accounts, store, conf.NetworkType)
-
-	group := graceland.NewGroup()
+	return command{}, false
+}
 
-	group.Add(service)
-	group.Add(store)
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: account-api <command> [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Configuration is read from FLOW_* environment variables. Commands:")
+	fmt.Fprintln(w)
 
-	err = group.Start()
-	if err != nil {
-		logger.Fatal().Err(err).Msg("failed to run server")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-45s %s\n", cmd.usage, cmd.description)
 	}
 }
 
@@ -133,10 +215,14 @@ func getPostgresConfig(conf Config, logger zerolog.Logger) pg.Config {
 			},
 		},
 		SetInternalPGLogger: conf.Environment != wallet.EnvironmentTest, // docker-compose will die from spam
+		LogQueries:          conf.PostgreSQLLogQueries,
+		Logger:              logger,
 		PGApplicationName:   conf.AppName,
 		PGLoggerPrefix:      conf.PostgresLoggerPrefix,
-		MigrationPath:       conf.PostgreSQLMigrationPath,
+		Migrations:          postgres.Migrations(),
+		MigrationMode:       conf.PostgreSQLMigrationMode,
 		PGPoolSize:          conf.PostgreSQLPoolSize,
+		QueryTimeout:        conf.PostgreSQLQueryTimeout,
 	}
 }
 
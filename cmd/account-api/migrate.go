@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/golang-migrate/migrate"
+	"github.com/rs/zerolog"
+
+	"github.com/onflow/flow-account-api/pkg/pg"
+)
+
+// runMigrate manages the schema of the Postgres database.
+//
+// The other storage backends have no schema to migrate.
+func runMigrate(args []string, conf Config, logger zerolog.Logger) error {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	confirm := flags.Bool("confirm", false, "confirm reverting every migration when running down")
+
+	if len(args) == 0 {
+		return fmt.Errorf("missing migrate action, must be up, down or version")
+	}
+
+	action := args[0]
+
+	err := flags.Parse(args[1:])
+	if err != nil {
+		return err
+	}
+
+	if conf.StorageBackend != storageBackendPostgres {
+		return fmt.Errorf("storage backend %q has no migrations", conf.StorageBackend)
+	}
+
+	db, err := pg.NewDatabase(getPostgresConfig(conf, logger))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+
+	var version uint
+
+	switch action {
+	case "up":
+		err = db.WithMigrationLock(ctx, func() error {
+			err := db.MigrateUp(ctx, &version)
+			// https://github.com/mattes/migrate/issues/287
+			if err != nil && err.Error() != migrate.ErrNoChange.Error() {
+				return err
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		logger.Info().Uint("version", version).Msg("migrated up")
+	case "down":
+		if !*confirm {
+			return fmt.Errorf("migrate down reverts every migration and drops all data, run again with -confirm")
+		}
+
+		err = db.WithMigrationLock(ctx, func() error {
+			err := db.MigrateDown(ctx)
+			if err != nil && err.Error() != migrate.ErrNoChange.Error() {
+				return err
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		logger.Info().Msg("migrated down")
+	case "version":
+		err = db.GetMigrationVersion(ctx, &version)
+		if err != nil {
+			return err
+		}
+
+		latest, err := db.LatestMigrationVersion()
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("current: %d\nlatest: %d\n", version, latest)
+	default:
+		return fmt.Errorf("unknown migrate action %q, must be up, down or version", action)
+	}
+
+	return nil
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/onflow/flow-account-api/model"
+)
+
+const (
+	formatNDJSON = "ndjson"
+	formatCSV    = "csv"
+)
+
+// accountRecord is the exported form of an account.
+//
+// Unlike the API representation it includes every stored field,
+// so that an export can be imported without losing data.
+type accountRecord struct {
+	*model.Account
+	ClientIP string `json:"clientIp,omitempty"`
+}
+
+// csvHeader lists the columns of a CSV export, which has one row per account public key.
+var csvHeader = []string{
+	"address",
+	"lockedAddress",
+	"creationTxId",
+	"createdAt",
+	"updatedAt",
+	"creationBlockHeight",
+	"network",
+	"creatorKeyIndex",
+	"clientId",
+	"clientIp",
+	"clientUserAgent",
+	"publicKey",
+	"signatureAlgorithm",
+	"hashAlgorithm",
+}
+
+// recordWriter writes accounts in an export format.
+type recordWriter interface {
+	Write(account *model.Account) error
+	Flush() error
+}
+
+// recordReader reads accounts in an export format, returning io.EOF after the last account.
+type recordReader interface {
+	Read() (*model.Account, error)
+}
+
+func newRecordWriter(format string, w io.Writer) (recordWriter, error) {
+	switch format {
+	case formatNDJSON:
+		buf := bufio.NewWriter(w)
+		return &ndjsonWriter{buf: buf, encoder: json.NewEncoder(buf)}, nil
+	case formatCSV:
+		writer := csv.NewWriter(w)
+		return &csvWriter{writer: writer}, writer.Write(csvHeader)
+	default:
+		return nil, fmt.Errorf("unknown format %q, must be %s or %s", format, formatNDJSON, formatCSV)
+	}
+}
+
+func newRecordReader(format string, r io.Reader) (recordReader, error) {
+	switch format {
+	case formatNDJSON:
+		return &ndjsonReader{decoder: json.NewDecoder(r)}, nil
+	case formatCSV:
+		reader := csv.NewReader(r)
+
+		header, err := reader.Read()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV header: %w", err)
+		}
+
+		if len(header) != len(csvHeader) {
+			return nil, fmt.Errorf("expected CSV header with %d columns, got %d", len(csvHeader), len(header))
+		}
+
+		return &csvReader{reader: reader}, nil
+	default:
+		return nil, fmt.Errorf("unknown format %q, must be %s or %s", format, formatNDJSON, formatCSV)
+	}
+}
+
+type ndjsonWriter struct {
+	buf     *bufio.Writer
+	encoder *json.Encoder
+}
+
+func (w *ndjsonWriter) Write(account *model.Account) error {
+	return w.encoder.Encode(&accountRecord{Account: account, ClientIP: account.ClientIP})
+}
+
+func (w *ndjsonWriter) Flush() error {
+	return w.buf.Flush()
+}
+
+type ndjsonReader struct {
+	decoder *json.Decoder
+}
+
+func (r *ndjsonReader) Read() (*model.Account, error) {
+	var record accountRecord
+
+	err := r.decoder.Decode(&record)
+	if err != nil {
+		return nil, err
+	}
+
+	if record.Account == nil {
+		return nil, fmt.Errorf("empty account record")
+	}
+
+	record.Account.ClientIP = record.ClientIP
+
+	return record.Account, nil
+}
+
+type csvWriter struct {
+	writer *csv.Writer
+}
+
+func (w *csvWriter) Write(account *model.Account) error {
+	for _, publicKey := range account.PublicKeys {
+		err := w.writer.Write([]string{
+			account.Address,
+			account.LockedAddress,
+			account.CreationTransactionID,
+			account.CreatedAt.Format(time.RFC3339Nano),
+			account.UpdatedAt.Format(time.RFC3339Nano),
+			strconv.FormatUint(account.CreationBlockHeight, 10),
+			account.Network,
+			strconv.Itoa(account.CreatorKeyIndex),
+			account.ClientID,
+			account.ClientIP,
+			account.ClientUserAgent,
+			publicKey.PublicKey,
+			publicKey.SigAlgo,
+			publicKey.HashAlgo,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (w *csvWriter) Flush() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+// csvReader groups consecutive rows with the same address into one account.
+type csvReader struct {
+	reader *csv.Reader
+	next   []string
+}
+
+func (r *csvReader) Read() (*model.Account, error) {
+	row, err := r.nextRow()
+	if err != nil {
+		return nil, err
+	}
+
+	account, err := parseCSVAccount(row)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		row, err := r.nextRow()
+		if err == io.EOF {
+			return account, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if row[0] != account.Address {
+			r.next = row
+			return account, nil
+		}
+
+		account.PublicKeys = append(account.PublicKeys, parseCSVPublicKey(row))
+	}
+}
+
+func (r *csvReader) nextRow() ([]string, error) {
+	if r.next != nil {
+		row := r.next
+		r.next = nil
+		return row, nil
+	}
+
+	return r.reader.Read()
+}
+
+func parseCSVAccount(row []string) (*model.Account, error) {
+	createdAt, err := parseCSVTime(row[3])
+	if err != nil {
+		return nil, fmt.Errorf("invalid createdAt for account %s: %w", row[0], err)
+	}
+
+	updatedAt, err := parseCSVTime(row[4])
+	if err != nil {
+		return nil, fmt.Errorf("invalid updatedAt for account %s: %w", row[0], err)
+	}
+
+	var creationBlockHeight uint64
+	if row[5] != "" {
+		creationBlockHeight, err = strconv.ParseUint(row[5], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid creationBlockHeight for account %s: %w", row[0], err)
+		}
+	}
+
+	var creatorKeyIndex int
+	if row[7] != "" {
+		creatorKeyIndex, err = strconv.Atoi(row[7])
+		if err != nil {
+			return nil, fmt.Errorf("invalid creatorKeyIndex for account %s: %w", row[0], err)
+		}
+	}
+
+	return &model.Account{
+		Address:               row[0],
+		LockedAddress:         row[1],
+		CreationTransactionID: row[2],
+		CreatedAt:             createdAt,
+		UpdatedAt:             updatedAt,
+		CreationBlockHeight:   creationBlockHeight,
+		Network:               row[6],
+		CreatorKeyIndex:       creatorKeyIndex,
+		ClientID:              row[8],
+		ClientIP:              row[9],
+		ClientUserAgent:       row[10],
+		PublicKeys:            []*model.AccountPublicKey{parseCSVPublicKey(row)},
+	}, nil
+}
+
+func parseCSVPublicKey(row []string) *model.AccountPublicKey {
+	return &model.AccountPublicKey{
+		PublicKey: row[11],
+		SigAlgo:   row[12],
+		HashAlgo:  row[13],
+	}
+}
+
+func parseCSVTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	return time.Parse(time.RFC3339Nano, value)
+}
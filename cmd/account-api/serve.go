@@ -0,0 +1,221 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/onflow/flow-go-sdk"
+	"github.com/onflow/flow-go-sdk/crypto"
+	"github.com/psiemens/graceland"
+	"github.com/rs/zerolog"
+
+	"github.com/onflow/flow-account-api/pkg/tracing"
+	"github.com/onflow/flow-account-api/storage"
+	"github.com/onflow/flow-account-api/wallet"
+)
+
+// runServe runs the HTTP and gRPC services until the process is stopped.
+func runServe(args []string, conf Config, logger zerolog.Logger) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	// routines such as the snapshotter and the account count refresher panic on invalid intervals
+	err = validateConfig(conf)
+	if err != nil {
+		return err
+	}
+
+	shutdownTracing, err := tracing.Setup(tracing.Config{
+		ServiceName:  conf.AppName,
+		Exporter:     conf.TracingExporter,
+		OTLPEndpoint: conf.TracingOTLPEndpoint,
+		SampleRatio:  conf.TracingSampleRatio,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set up tracing: %w", err)
+	}
+
+	defer shutdownTracing()
+
+	// the store is opened before any routine starts, since every routine uses it,
+	// and closed only once they have all stopped
+	store, closeStore, err := openStore(conf, logger)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		closeErr := closeStore()
+		if closeErr != nil {
+			logger.Error().Err(closeErr).Msg("failed to close store")
+		}
+	}()
+
+	webhooks, err := readWebhookSubscriptions(conf.WebhooksPath)
+	if err != nil {
+		return err
+	}
+
+	metrics := wallet.NewAccountsCollector(conf.NetworkType)
+
+	service, err := newService(conf, logger, store, metrics, webhooks)
+	if err != nil {
+		return err
+	}
+
+	grpcService := wallet.NewGRPCService(conf.GRPCPort, service)
+
+	// the service and the background routines each wrap the store to record the latency of their queries
+	accountCountRefresher := wallet.NewAccountCountRefresher(
+		store,
+		metrics,
+		conf.AccountCountRefreshInterval,
+		logger,
+	)
+
+	webhookDispatcher := wallet.NewWebhookDispatcher(
+		store,
+		wallet.WebhookConfig{
+			Subscriptions: webhooks,
+			Timeout:       conf.WebhookTimeout,
+			MaxAttempts:   conf.WebhookMaxAttempts,
+			RetryDelay:    conf.WebhookRetryDelay,
+			MaxRetryDelay: conf.WebhookMaxRetryDelay,
+			PollInterval:  conf.WebhookPollInterval,
+		},
+		metrics,
+		logger,
+	)
+
+	internalServer := wallet.NewInternalServer(getServerConfig(conf), service)
+
+	// routines are stopped in the order they are added, so the service drains
+	// the account creations in flight while the internal server still reports it as unready
+	group := graceland.NewGroup()
+
+	group.Add(service)
+	group.Add(grpcService)
+	group.Add(internalServer)
+	group.Add(accountCountRefresher)
+
+	// deliveries are only queued for configured subscriptions, so without any there is nothing to dispatch
+	if len(webhooks) > 0 {
+		group.Add(webhookDispatcher)
+	}
+
+	if snapshotter := newSnapshotter(conf, store, logger); snapshotter != nil {
+		group.Add(snapshotter)
+	}
+
+	err = group.Start()
+	if err != nil {
+		return fmt.Errorf("failed to run server: %w", err)
+	}
+
+	return nil
+}
+
+// newService creates the account service backed by the given store,
+// queueing webhooks for the given subscriptions.
+func newService(
+	conf Config,
+	logger zerolog.Logger,
+	store storage.Store,
+	metrics *wallet.AccountsCollector,
+	webhooks []wallet.WebhookSubscription,
+) (*wallet.Service, error) {
+	accounts, err := newAccounts(conf, metrics, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return wallet.NewService(
+		getServerConfig(conf),
+		logger,
+		accounts,
+		store,
+		metrics,
+		webhooks,
+		conf.ShutdownTimeout,
+		conf.SingleAccountLookup,
+	), nil
+}
+
+// getServerConfig returns the configuration of the HTTP servers.
+func getServerConfig(conf Config) wallet.ServerConfig {
+	return wallet.ServerConfig{
+		Port:              conf.Port,
+		InternalAddress:   conf.InternalAddress,
+		ReadTimeout:       conf.HTTPReadTimeout,
+		ReadHeaderTimeout: conf.HTTPReadHeaderTimeout,
+		WriteTimeout:      conf.HTTPWriteTimeout,
+		IdleTimeout:       conf.HTTPIdleTimeout,
+		MaxBodyBytes:      conf.HTTPMaxBodyBytes,
+		TLSCertPath:       conf.TLSCertPath,
+		TLSKeyPath:        conf.TLSKeyPath,
+	}
+}
+
+// readWebhookSubscriptions reads the webhook subscriptions file, if one is configured.
+func readWebhookSubscriptions(path string) ([]wallet.WebhookSubscription, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open webhook subscriptions: %w", err)
+	}
+	defer f.Close()
+
+	return wallet.ReadWebhookSubscriptions(f)
+}
+
+// newAccounts creates the account creator from the configured creator key and access API.
+func newAccounts(conf Config, metrics *wallet.AccountsCollector, logger zerolog.Logger) (*wallet.Accounts, error) {
+	creatorSigner, err := newCreatorSigner(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts, err := wallet.NewAccounts(
+		conf.AccessAPIHost,
+		flow.HexToAddress(conf.CreatorAddress),
+		conf.CreatorKeyIndex,
+		creatorSigner,
+		conf.AccountLimit,
+		conf.NetworkType,
+		conf.SealTimeout,
+		metrics,
+		logger,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to access API: %w", err)
+	}
+
+	return accounts, nil
+}
+
+func newCreatorSigner(conf Config) (crypto.Signer, error) {
+	creatorKeySigAlgo := crypto.StringToSignatureAlgorithm(conf.CreatorKeySigAlgo)
+	if creatorKeySigAlgo == crypto.UnknownSignatureAlgorithm {
+		return nil, fmt.Errorf("unknown creator key signature algorithm %q", conf.CreatorKeySigAlgo)
+	}
+
+	creatorKeyHashAlgo := crypto.StringToHashAlgorithm(conf.CreatorKeyHashAlgo)
+	if creatorKeyHashAlgo == crypto.UnknownHashAlgorithm {
+		return nil, fmt.Errorf("unknown creator key hash algorithm %q", conf.CreatorKeyHashAlgo)
+	}
+
+	creatorPrivateKey, err := crypto.DecodePrivateKeyHex(creatorKeySigAlgo, conf.CreatorPrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid creator private key: %w", err)
+	}
+
+	return crypto.NewInMemorySigner(creatorPrivateKey, creatorKeyHashAlgo), nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/rs/zerolog"
+
+	"github.com/onflow/flow-account-api/storage"
+	"github.com/onflow/flow-account-api/storage/bolt"
+	"github.com/onflow/flow-account-api/storage/memory"
+	"github.com/onflow/flow-account-api/storage/postgres"
+)
+
+const (
+	storageBackendMemory   = "memory"
+	storageBackendPostgres = "postgres"
+	storageBackendBolt     = "bolt"
+)
+
+// managedStore is a storage backend that is opened before it is used and closed once it is no longer needed.
+//
+// Postgres connects to the database and migrates it in Open, and Bolt opens its database file.
+// The memory store is usable as soon as it is created, but is managed the same way
+// so that callers need not tell backends apart.
+type managedStore interface {
+	storage.Store
+	Open() error
+	Close() error
+}
+
+// newStore creates the storage backend selected by the configuration.
+func newStore(conf Config, logger zerolog.Logger) (managedStore, error) {
+	switch conf.StorageBackend {
+	case storageBackendMemory:
+		return memory.NewStore(), nil
+	case storageBackendPostgres:
+		store, err := postgres.NewStore(getPostgresConfig(conf, logger), conf.Environment, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize Postgres database: %w", err)
+		}
+
+		return store, nil
+	case storageBackendBolt:
+		return bolt.NewStore(conf.BoltPath), nil
+	default:
+		return nil, fmt.Errorf(
+			"unknown storage backend %q, must be one of %s, %s or %s",
+			conf.StorageBackend,
+			storageBackendMemory,
+			storageBackendPostgres,
+			storageBackendBolt,
+		)
+	}
+}
+
+// openStore creates and opens the configured store, restoring the memory store from its snapshot
+// if snapshots are enabled. The returned function closes the store, saving a final snapshot
+// of the memory store first.
+func openStore(conf Config, logger zerolog.Logger) (managedStore, func() error, error) {
+	store, err := newStore(conf, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = store.Open()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open %s store: %w", conf.StorageBackend, err)
+	}
+
+	memoryStore, ok := store.(*memory.Store)
+	if !ok || conf.MemorySnapshotPath == "" {
+		return store, store.Close, nil
+	}
+
+	err = memoryStore.LoadSnapshot(conf.MemorySnapshotPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to restore memory store snapshot: %w", err)
+	}
+
+	return store, func() error {
+		err := memoryStore.SaveSnapshot(conf.MemorySnapshotPath)
+		if err != nil {
+			return fmt.Errorf("failed to save memory store snapshot: %w", err)
+		}
+
+		return store.Close()
+	}, nil
+}
+
+// newSnapshotter returns a routine that periodically saves snapshots of the memory store,
+// or nil if the store is not a memory store or snapshots are disabled.
+func newSnapshotter(conf Config, store storage.Store, logger zerolog.Logger) *memory.Snapshotter {
+	memoryStore, ok := store.(*memory.Store)
+	if !ok || conf.MemorySnapshotPath == "" {
+		return nil
+	}
+
+	return memory.NewSnapshotter(
+		memoryStore,
+		conf.MemorySnapshotPath,
+		conf.MemorySnapshotInterval,
+		logger,
+	)
+}
@@ -1,6 +1,6 @@
 module github.com/onflow/flow-account-api
 
-go 1.14
+go 1.16
 
 require (
 	github.com/Azure/go-ansiterm v0.0.0-20170929234023-d6e3b3328b78 // indirect
@@ -13,6 +13,7 @@ require (
 	github.com/go-pg/pg/v10 v10.0.2
 	github.com/gogo/protobuf v1.3.1 // indirect
 	github.com/golang-migrate/migrate v3.5.4+incompatible
+	github.com/golang/protobuf v1.4.3
 	github.com/gorilla/mux v1.8.0
 	github.com/lib/pq v1.8.0 // indirect
 	github.com/onflow/flow-go-sdk v0.21.0
@@ -24,6 +25,12 @@ require (
 	github.com/psiemens/sconfig v0.0.0-20190623041652-6e01eb1354fc
 	github.com/rs/cors v0.0.0-20160617231935-a62a804a8a00
 	github.com/rs/zerolog v1.19.0
+	go.etcd.io/bbolt v1.3.5
+	go.opentelemetry.io/otel v0.11.0
+	go.opentelemetry.io/otel/exporters/otlp v0.11.0
+	go.opentelemetry.io/otel/exporters/stdout v0.11.0
+	go.opentelemetry.io/otel/sdk v0.11.0
 	golang.org/x/xerrors v0.0.0-20200804184101-5ec99f83aff1
 	google.golang.org/grpc v1.39.0
+	google.golang.org/protobuf v1.25.0
 )
@@ -1,16 +1,35 @@
 package model
 
+import "time"
+
 type Account struct {
 	tableName             struct{}            `pg:"accounts"`
 	Address               string              `json:"address" pg:"address,pk"`
 	LockedAddress         string              `json:"lockedAddress" pg:"locked_address"`
 	CreationTransactionID string              `json:"creationTxId" pg:"creation_tx_id"`
 	PublicKeys            []*AccountPublicKey `json:"publicKeys" pg:"rel:has-many"`
+	CreatedAt             time.Time           `json:"createdAt" pg:"created_at"`
+	UpdatedAt             time.Time           `json:"updatedAt" pg:"updated_at"`
+	CreationBlockHeight   uint64              `json:"creationBlockHeight,omitempty" pg:"creation_block_height"` // reference block of the creation transaction
+	Network               string              `json:"network,omitempty" pg:"network"`
+	CreatorKeyIndex       int                 `json:"creatorKeyIndex" pg:"creator_key_index"`
+	ClientID              string              `json:"clientId,omitempty" pg:"client_id"`
+	ClientIP              string              `json:"-" pg:"client_ip"` // personal data, not exposed by the API
+	ClientUserAgent       string              `json:"clientUserAgent,omitempty" pg:"client_user_agent"`
+}
+
+// ClientMetadata describes the API client on whose behalf an account is created.
+type ClientMetadata struct {
+	ID        string
+	IP        string
+	UserAgent string
+	// Admin is set when an operator creates the account from the command line.
+	Admin bool
 }
 
 type AccountPublicKey struct {
 	tableName      struct{} `pg:"public_keys"`
-	AccountAddress string   `json:"-" pg:"account_address"`
+	AccountAddress string   `json:"-" pg:"account_address,pk"`
 	PublicKey      string   `json:"publicKey" pg:"public_key,pk"`
 	SigAlgo        string   `json:"signatureAlgorithm" pg:"sig_algo"`
 	HashAlgo       string   `json:"hashAlgorithm" pg:"hash_algo"`
@@ -0,0 +1,46 @@
+package model
+
+import "time"
+
+// Types of audit events.
+const (
+	// AuditEventAccountCreated records an account created on chain and stored.
+	AuditEventAccountCreated = "account_created"
+	// AuditEventAccountCreationFailed records a rejected or failed attempt to create an account.
+	AuditEventAccountCreationFailed = "account_creation_failed"
+	// AuditEventAccountImported records an account inserted by the import command.
+	AuditEventAccountImported = "account_imported"
+)
+
+// Actors that cause audit events.
+const (
+	// AuditActorClient is an API client creating an account over HTTP or gRPC.
+	AuditActorClient = "client"
+	// AuditActorAdmin is an operator running a command against the registry.
+	AuditActorAdmin = "admin"
+)
+
+// AuditEvent records a change to the registry, or a failed attempt to make one.
+//
+// Audit events are append-only: stores never update or delete them.
+//
+// The claimed client ID is whatever the caller sent in the X-Client-ID header or client-id metadata.
+// Nothing authenticates it, so it identifies the client only as far as the caller can be trusted.
+type AuditEvent struct {
+	tableName  struct{}  `pg:"audit_events"`
+	ID         int64     `json:"id" pg:"id,pk"`
+	CreatedAt  time.Time `json:"createdAt" pg:"created_at"`
+	Type       string    `json:"type" pg:"type"`
+	Actor      string    `json:"actor" pg:"actor"`
+	Address    string    `json:"address,omitempty" pg:"address"`
+	PublicKeys []string  `json:"publicKeys,omitempty" pg:"public_keys,array"`
+	// RejectedPublicKey is a public key that failed to decode, exactly as the client sent it.
+	// Keys that decode are recorded in PublicKeys in their canonical encoding instead.
+	RejectedPublicKey string `json:"rejectedPublicKey,omitempty" pg:"rejected_public_key"`
+	TransactionID     string `json:"transactionId,omitempty" pg:"transaction_id"`
+	RequestID         string `json:"requestId,omitempty" pg:"request_id"`
+	ClaimedClientID   string `json:"claimedClientId,omitempty" pg:"claimed_client_id"`
+	ClientIP          string `json:"clientIp,omitempty" pg:"client_ip"`
+	ClientUserAgent   string `json:"clientUserAgent,omitempty" pg:"client_user_agent"`
+	Error             string `json:"error,omitempty" pg:"error"`
+}
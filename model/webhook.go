@@ -0,0 +1,37 @@
+package model
+
+import "time"
+
+// Types of events sent to webhook subscriptions.
+const (
+	// WebhookEventAccountCreated is sent when an account has been created on chain and stored.
+	WebhookEventAccountCreated = "account.created"
+	// WebhookEventAccountCreationFailed is sent when a valid request to create an account failed on chain or in the store.
+	WebhookEventAccountCreationFailed = "account.creation_failed"
+)
+
+// Statuses of webhook deliveries.
+const (
+	// WebhookDeliveryPending deliveries are waiting for their next attempt.
+	WebhookDeliveryPending = "pending"
+	// WebhookDeliveryDelivered deliveries were accepted by the subscriber.
+	WebhookDeliveryDelivered = "delivered"
+	// WebhookDeliveryDead deliveries were given up on after too many failed attempts.
+	WebhookDeliveryDead = "dead"
+)
+
+// WebhookDelivery is an event queued in the outbox for delivery to one webhook subscription.
+type WebhookDelivery struct {
+	tableName     struct{}  `pg:"webhook_deliveries"`
+	ID            int64     `json:"id" pg:"id,pk"`
+	CreatedAt     time.Time `json:"createdAt" pg:"created_at"`
+	EventID       string    `json:"eventId" pg:"event_id"`
+	EventType     string    `json:"eventType" pg:"event_type"`
+	Subscription  string    `json:"subscription" pg:"subscription"`
+	Payload       string    `json:"payload" pg:"payload"` // JSON body sent to the subscriber
+	Status        string    `json:"status" pg:"status"`
+	Attempts      int       `json:"attempts" pg:"attempts,use_zero"`
+	NextAttemptAt time.Time `json:"nextAttemptAt" pg:"next_attempt_at"`
+	LastAttemptAt time.Time `json:"lastAttemptAt,omitempty" pg:"last_attempt_at"`
+	LastError     string    `json:"lastError,omitempty" pg:"last_error"`
+}
@@ -0,0 +1,60 @@
+// Package logging configures the service logger and carries request-scoped loggers in contexts.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Formats that log lines can be written in.
+const (
+	// FormatJSON writes one JSON object per line.
+	FormatJSON = "json"
+	// FormatConsole writes human-readable lines, for local development.
+	FormatConsole = "console"
+)
+
+// New creates a logger that writes timestamped lines at or above the given level.
+func New(w io.Writer, level, format string) (zerolog.Logger, error) {
+	lvl, err := zerolog.ParseLevel(level)
+	if err != nil {
+		return zerolog.Logger{}, fmt.Errorf("invalid log level %q: %w", level, err)
+	}
+
+	switch format {
+	case FormatJSON:
+	case FormatConsole:
+		w = zerolog.ConsoleWriter{Out: w, TimeFormat: time.RFC3339}
+	default:
+		return zerolog.Logger{}, fmt.Errorf(
+			"unknown log format %q, must be one of %s or %s",
+			format,
+			FormatJSON,
+			FormatConsole,
+		)
+	}
+
+	return zerolog.New(w).Level(lvl).With().Timestamp().Logger(), nil
+}
+
+// FromContext returns the logger carried by the context, or the fallback if it carries none.
+func FromContext(ctx context.Context, fallback zerolog.Logger) *zerolog.Logger {
+	logger := zerolog.Ctx(ctx)
+	if logger.GetLevel() == zerolog.Disabled {
+		return &fallback
+	}
+
+	return logger
+}
+
+// With returns a copy of the context whose logger adds the given fields to every line.
+//
+// Loggers carried by the parent context are not changed.
+func With(ctx context.Context, fallback zerolog.Logger, fields func(c zerolog.Context) zerolog.Context) context.Context {
+	logger := fields(FromContext(ctx, fallback).With()).Logger()
+	return logger.WithContext(ctx)
+}
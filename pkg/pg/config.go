@@ -2,19 +2,36 @@ package pg
 
 import (
 	"crypto/tls"
+	"io/fs"
 	"time"
+
+	"github.com/rs/zerolog"
 )
 
 // Config is the service configuration
 type Config struct {
 	ConnectPGOptions
 	SetInternalPGLogger bool
+	LogQueries          bool // log every query, tagged with the request that made it
+	Logger              zerolog.Logger
 	PGApplicationName   string
 	PGLoggerPrefix      string
-	MigrationPath       string
+	Migrations          fs.FS  // migration files, named as golang-migrate expects
+	MigrationMode       string // one of the MigrationMode constants
 	PGPoolSize          int
+	QueryTimeout        time.Duration // bounds each query; zero means no timeout
 }
 
+// Migration modes control what happens to the schema when a store is opened.
+const (
+	// MigrationModeAuto applies pending migrations, holding a lock so that only one process migrates at a time.
+	MigrationModeAuto = "auto"
+	// MigrationModeVerify fails if any migrations are pending, leaving them to be applied separately.
+	MigrationModeVerify = "verify"
+	// MigrationModeOff skips both migrating and verifying the schema.
+	MigrationModeOff = "off"
+)
+
 // ConnectPGOptions attempts to connect to a pg instance;
 // retries `RetryNumTimes`
 type ConnectPGOptions struct {
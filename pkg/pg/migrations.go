@@ -0,0 +1,177 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/golang-migrate/migrate"
+	"github.com/golang-migrate/migrate/source"
+)
+
+// migrationLockID identifies the advisory lock held while migrating,
+// so that only one process migrates a database at a time.
+const migrationLockID int64 = 0x666c6f7761706931
+
+// newMigrator creates a migrator that reads migrations from the configured file system.
+func (d *Database) newMigrator() (*migrate.Migrate, error) {
+	src, err := newFSSource(d.migrations)
+	if err != nil {
+		return nil, err
+	}
+
+	return migrate.NewWithSourceInstance("fs", src, d.connectionString)
+}
+
+// LatestMigrationVersion returns the version of the last available migration,
+// which is the version of an up to date schema.
+func (d *Database) LatestMigrationVersion() (uint, error) {
+	src, err := newFSSource(d.migrations)
+	if err != nil {
+		return 0, err
+	}
+
+	version, ok := src.migrations.First()
+	if !ok {
+		return 0, errors.New("pg: no migrations found")
+	}
+
+	for {
+		next, ok := src.migrations.Next(version)
+		if !ok {
+			return version, nil
+		}
+
+		version = next
+	}
+}
+
+// WithMigrationLock runs fn while holding a session-level advisory lock,
+// blocking until any other process holding the lock releases it.
+func (d *Database) WithMigrationLock(ctx context.Context, fn func() error) (err error) {
+	// session locks belong to a connection, so the lock and unlock must use the same one
+	conn := d.DB.Conn()
+	defer conn.Close()
+
+	_, err = conn.ExecContext(ctx, "SELECT pg_advisory_lock(?)", migrationLockID)
+	if err != nil {
+		return fmt.Errorf("pg: failed to acquire migration lock: %w", err)
+	}
+
+	defer func() {
+		_, unlockErr := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock(?)", migrationLockID)
+		if err == nil && unlockErr != nil {
+			err = fmt.Errorf("pg: failed to release migration lock: %w", unlockErr)
+		}
+	}()
+
+	return fn()
+}
+
+// fsSource is a golang-migrate source driver that reads migrations from a file system,
+// such as one embedded in the binary.
+type fsSource struct {
+	fsys       fs.FS
+	migrations *source.Migrations
+}
+
+var _ source.Driver = &fsSource{}
+
+func newFSSource(fsys fs.FS) (*fsSource, error) {
+	if fsys == nil {
+		return nil, errors.New("pg: no migrations configured")
+	}
+
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &fsSource{
+		fsys:       fsys,
+		migrations: source.NewMigrations(),
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		m, err := source.DefaultParse(entry.Name())
+		if err != nil {
+			continue // ignore files that are not migrations
+		}
+
+		if !s.migrations.Append(m) {
+			return nil, fmt.Errorf("pg: duplicate migration %s", entry.Name())
+		}
+	}
+
+	return s, nil
+}
+
+// Open is not supported, since the source is created from a file system rather than a URL.
+func (s *fsSource) Open(url string) (source.Driver, error) {
+	return nil, errors.New("pg: fs source cannot be opened from a URL")
+}
+
+func (s *fsSource) Close() error {
+	return nil
+}
+
+func (s *fsSource) First() (uint, error) {
+	version, ok := s.migrations.First()
+	if !ok {
+		return 0, &os.PathError{Op: "first", Path: ".", Err: os.ErrNotExist}
+	}
+
+	return version, nil
+}
+
+func (s *fsSource) Prev(version uint) (uint, error) {
+	prev, ok := s.migrations.Prev(version)
+	if !ok {
+		return 0, &os.PathError{Op: fmt.Sprintf("prev for version %v", version), Path: ".", Err: os.ErrNotExist}
+	}
+
+	return prev, nil
+}
+
+func (s *fsSource) Next(version uint) (uint, error) {
+	next, ok := s.migrations.Next(version)
+	if !ok {
+		return 0, &os.PathError{Op: fmt.Sprintf("next for version %v", version), Path: ".", Err: os.ErrNotExist}
+	}
+
+	return next, nil
+}
+
+func (s *fsSource) ReadUp(version uint) (io.ReadCloser, string, error) {
+	m, ok := s.migrations.Up(version)
+	if !ok {
+		return nil, "", &os.PathError{Op: fmt.Sprintf("read version %v", version), Path: ".", Err: os.ErrNotExist}
+	}
+
+	return s.open(m)
+}
+
+func (s *fsSource) ReadDown(version uint) (io.ReadCloser, string, error) {
+	m, ok := s.migrations.Down(version)
+	if !ok {
+		return nil, "", &os.PathError{Op: fmt.Sprintf("read version %v", version), Path: ".", Err: os.ErrNotExist}
+	}
+
+	return s.open(m)
+}
+
+func (s *fsSource) open(m *source.Migration) (io.ReadCloser, string, error) {
+	f, err := s.fsys.Open(m.Raw)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return f, m.Identifier, nil
+}
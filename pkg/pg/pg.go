@@ -3,22 +3,30 @@ package pg
 import (
 	"context"
 	"errors"
-	"log"
+	"fmt"
+	"io/fs"
 	"net/url"
-	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-pg/pg/v10"
 	"github.com/golang-migrate/migrate"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/api/trace"
+	"go.opentelemetry.io/otel/label"
+	"go.opentelemetry.io/otel/semconv"
 	"golang.org/x/xerrors"
+
+	"github.com/onflow/flow-account-api/pkg/logging"
+	"github.com/onflow/flow-account-api/pkg/tracing"
 )
 
 // Database is a connection to a Postgres database.
 type Database struct {
 	*pg.DB
 	version          uint
-	migrationPath    string
+	migrations       fs.FS
 	connectionString string
 }
 
@@ -37,14 +45,22 @@ func NewDatabase(conf Config) (*Database, error) {
 		return nil, err
 	}
 
+	logger := conf.Logger.With().Str("component", conf.PGLoggerPrefix).Logger()
+
 	// set query logger
 	if conf.SetInternalPGLogger {
-		pg.SetLogger(&logger{log.New(os.Stdout, conf.PGLoggerPrefix, log.LstdFlags)})
+		pg.SetLogger(&internalLogger{logger})
+	}
+
+	db.AddQueryHook(newQueryTracer(ops))
+
+	if conf.LogQueries {
+		db.AddQueryHook(&queryLogger{logger})
 	}
 
 	provider := &Database{
 		DB:               db,
-		migrationPath:    conf.MigrationPath,
+		migrations:       conf.Migrations,
 		connectionString: conf.ConnectionString,
 	}
 
@@ -66,12 +82,37 @@ func (d *Database) Close() error {
 }
 
 // GetMigrationVersion returns what migration we are on.
+//
+// Zero means that no migrations have been applied.
 func (d *Database) GetMigrationVersion(ctx context.Context, v *uint) error {
 	return d.DB.RunInTransaction(ctx, func(tx *pg.Tx) error {
 		if v == nil {
 			return errors.New("pg: migration version (v) is nil")
 		}
-		*v = d.version
+
+		migrator, err := d.newMigrator()
+		if err != nil {
+			return err
+		}
+
+		defer migrator.Close()
+
+		version, dirty, err := migrator.Version()
+		if err != nil {
+			if err.Error() != migrate.ErrNilVersion.Error() {
+				return err
+			}
+
+			version = 0
+		}
+
+		if dirty {
+			return fmt.Errorf("pg: migration %d is dirty", version)
+		}
+
+		d.version = version
+		*v = version
+
 		return nil
 	})
 }
@@ -83,7 +124,7 @@ func (d *Database) MigrateUp(ctx context.Context, version *uint) error {
 			return errors.New("pg: migration version (v) is nil")
 		}
 
-		migrator, err := migrate.New("file://"+d.migrationPath, d.connectionString)
+		migrator, err := d.newMigrator()
 		if err != nil {
 			return err
 		}
@@ -136,7 +177,7 @@ func (d *Database) MigrateUp(ctx context.Context, version *uint) error {
 // MigrateDown performs a down migration.
 func (d *Database) MigrateDown(ctx context.Context) error {
 	return d.DB.RunInTransaction(ctx, func(tx *pg.Tx) error {
-		m, err := migrate.New("file://"+d.migrationPath, d.connectionString)
+		m, err := d.newMigrator()
 		if err != nil {
 			return err
 		}
@@ -176,14 +217,18 @@ func (d *Database) TruncateAll() error {
 	})
 }
 
-func (d *Database) RunInTransaction(ctx context.Context, next func(ctx context.Context) error) error {
-	tx, err := d.DB.Begin()
+// RunInTransaction runs next in a transaction, which is committed if next
+// returns nil and rolled back otherwise.
+//
+// Queries must be issued through the given transaction to take part in it.
+func (d *Database) RunInTransaction(ctx context.Context, next func(tx *pg.Tx) error) error {
+	tx, err := d.DB.BeginContext(ctx)
 	if err != nil {
 		return err
 	}
 
 	return tx.RunInTransaction(ctx, func(tx *pg.Tx) error {
-		return convertError(next(ctx))
+		return convertError(next(tx))
 	})
 }
 
@@ -242,10 +287,96 @@ func parseURL(sURL string) (*pg.Options, error) {
 	return options, nil
 }
 
-type logger struct {
-	log *log.Logger
+// internalLogger writes the messages of the pg package to the logger of the request that caused them.
+type internalLogger struct {
+	logger zerolog.Logger
+}
+
+func (l *internalLogger) Printf(ctx context.Context, format string, v ...interface{}) {
+	logging.FromContext(ctx, l.logger).Info().Msgf(format, v...)
+}
+
+// queryLogger logs each query once it has completed.
+type queryLogger struct {
+	logger zerolog.Logger
+}
+
+func (l *queryLogger) BeforeQuery(ctx context.Context, _ *pg.QueryEvent) (context.Context, error) {
+	return ctx, nil
+}
+
+func (l *queryLogger) AfterQuery(ctx context.Context, event *pg.QueryEvent) error {
+	query, err := event.FormattedQuery()
+	if err != nil {
+		return err
+	}
+
+	logging.FromContext(ctx, l.logger).Info().
+		Bytes("query", query).
+		Dur("duration", time.Since(event.StartTime)).
+		AnErr("queryError", event.Err).
+		Msg("executed query")
+
+	return nil
+}
+
+// queryTracer traces each query as a child of any span in its context.
+//
+// Spans carry the query template rather than the formatted query, so that the
+// parameters of a query, such as public keys, are not exported with its trace.
+type queryTracer struct {
+	attrs []label.KeyValue
 }
 
-func (l *logger) Printf(ctx context.Context, format string, v ...interface{}) {
-	l.log.Printf(format, v...)
+func newQueryTracer(ops *pg.Options) *queryTracer {
+	return &queryTracer{
+		attrs: []label.KeyValue{
+			semconv.DBSystemPostgres,
+			semconv.DBNameKey.String(ops.Database),
+			semconv.DBUserKey.String(ops.User),
+		},
+	}
+}
+
+type querySpanKey struct{}
+
+func (t *queryTracer) BeforeQuery(ctx context.Context, event *pg.QueryEvent) (context.Context, error) {
+	query, err := event.UnformattedQuery()
+	if err != nil {
+		return ctx, err
+	}
+
+	statement := strings.TrimSpace(string(query))
+
+	operation := strings.ToUpper(strings.SplitN(statement, " ", 2)[0])
+	if operation == "" {
+		operation = "QUERY"
+	}
+
+	attrs := append([]label.KeyValue{
+		semconv.DBStatementKey.String(statement),
+		semconv.DBOperationKey.String(operation),
+	}, t.attrs...)
+
+	ctx, span := tracing.StartSpan(ctx, "pg."+operation, attrs...)
+
+	return context.WithValue(ctx, querySpanKey{}, span), nil
+}
+
+func (t *queryTracer) AfterQuery(ctx context.Context, event *pg.QueryEvent) error {
+	span, ok := ctx.Value(querySpanKey{}).(trace.Span)
+	if !ok {
+		return nil
+	}
+
+	err := event.Err
+
+	// a query that finds no rows is an expected outcome rather than a failure
+	if errors.Is(err, pg.ErrNoRows) {
+		err = nil
+	}
+
+	tracing.EndSpan(ctx, span, err)
+
+	return nil
 }
@@ -0,0 +1,134 @@
+// Package tracing configures OpenTelemetry tracing for the service.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel/api/global"
+	"go.opentelemetry.io/otel/api/trace"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp"
+	"go.opentelemetry.io/otel/exporters/stdout"
+	"go.opentelemetry.io/otel/label"
+	export "go.opentelemetry.io/otel/sdk/export/trace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/semconv"
+)
+
+// Exporters that spans can be sent to.
+const (
+	// ExporterNone disables tracing.
+	ExporterNone = "none"
+	// ExporterStdout writes spans to standard output, for local debugging.
+	ExporterStdout = "stdout"
+	// ExporterOTLP sends spans to an OpenTelemetry collector.
+	ExporterOTLP = "otlp"
+)
+
+// instrumentationName identifies the spans created by this service.
+const instrumentationName = "github.com/onflow/flow-account-api"
+
+// Config is the tracing configuration.
+type Config struct {
+	ServiceName  string
+	Exporter     string
+	OTLPEndpoint string  // address of the collector when using the OTLP exporter
+	SampleRatio  float64 // fraction of new traces to sample; traces started by callers follow their decision
+}
+
+// Setup installs the global trace provider described by the configuration.
+//
+// The returned function flushes any buffered spans and must be called before the process exits.
+func Setup(conf Config) (func(), error) {
+	var (
+		exporter export.SpanBatcher
+		stop     func()
+	)
+
+	switch conf.Exporter {
+	case ExporterNone, "":
+		return func() {}, nil
+	case ExporterStdout:
+		e, err := stdout.NewExporter(
+			stdout.WithWriter(os.Stdout),
+			stdout.WithoutMetricExport(),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		exporter, stop = e, func() {}
+	case ExporterOTLP:
+		e, err := otlp.NewExporter(
+			otlp.WithInsecure(),
+			otlp.WithAddress(conf.OTLPEndpoint),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+		}
+
+		exporter, stop = e, func() { _ = e.Stop() }
+	default:
+		return nil, fmt.Errorf(
+			"unknown tracing exporter %q, must be one of %s, %s or %s",
+			conf.Exporter,
+			ExporterNone,
+			ExporterStdout,
+			ExporterOTLP,
+		)
+	}
+
+	processor, err := sdktrace.NewBatchSpanProcessor(exporter)
+	if err != nil {
+		stop()
+		return nil, err
+	}
+
+	provider, err := sdktrace.NewProvider(
+		sdktrace.WithConfig(sdktrace.Config{
+			DefaultSampler: sdktrace.ParentSample(sdktrace.ProbabilitySampler(conf.SampleRatio)),
+		}),
+		sdktrace.WithResource(resource.New(semconv.ServiceNameKey.String(conf.ServiceName))),
+	)
+	if err != nil {
+		stop()
+		return nil, err
+	}
+
+	provider.RegisterSpanProcessor(processor)
+
+	global.SetTraceProvider(provider)
+
+	return func() {
+		// shutting down the processor exports the spans it has buffered
+		processor.Shutdown()
+		stop()
+	}, nil
+}
+
+// StartSpan starts a span as a child of any span in the context.
+func StartSpan(ctx context.Context, name string, attrs ...label.KeyValue) (context.Context, trace.Span) {
+	return global.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// EndSpan records the outcome of an operation on its span and ends it.
+func EndSpan(ctx context.Context, span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(ctx, err, trace.WithErrorStatus(codes.Unknown))
+	}
+
+	span.End()
+}
+
+// TraceID returns the ID of the trace in the context, or an empty string if it is not being traced.
+func TraceID(ctx context.Context) string {
+	spanContext := trace.SpanFromContext(ctx).SpanContext()
+	if !spanContext.HasTraceID() {
+		return ""
+	}
+
+	return spanContext.TraceID.String()
+}
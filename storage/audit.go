@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"github.com/onflow/flow-account-api/model"
+)
+
+// AuditEventFilter restricts the events returned by ListAuditEvents.
+//
+// Zero values match all events.
+type AuditEventFilter struct {
+	// Address matches events about this account.
+	Address string
+	// PublicKey matches events about accounts with this public key.
+	PublicKey string
+	// Type matches events of this type.
+	Type string
+}
+
+// Matches reports whether an event satisfies the filter.
+func (f AuditEventFilter) Matches(event *model.AuditEvent) bool {
+	if f.Address != "" && event.Address != f.Address {
+		return false
+	}
+
+	if f.Type != "" && event.Type != f.Type {
+		return false
+	}
+
+	if f.PublicKey == "" {
+		return true
+	}
+
+	for _, publicKey := range event.PublicKeys {
+		if publicKey == f.PublicKey {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AuditEventPage is a page of audit events in the order they were recorded.
+type AuditEventPage struct {
+	Events []*model.AuditEvent `json:"events"`
+	// NextCursor resumes the listing after the last event in this page.
+	// It is empty if there are no more events.
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// NewAuditEventPage returns a page of at most limit events from events,
+// which may hold one more event than the limit to signal that there is another page.
+func NewAuditEventPage(events []*model.AuditEvent, limit int) *AuditEventPage {
+	page := &AuditEventPage{Events: events}
+
+	if len(events) > limit {
+		page.Events = events[:limit]
+		page.NextCursor = EncodeIDCursor(page.Events[limit-1].ID)
+	}
+
+	return page
+}
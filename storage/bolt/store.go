@@ -0,0 +1,656 @@
+// Package bolt implements storage.Store in a single embedded database file,
+// for local development and small deployments that do not run Postgres.
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/onflow/flow-account-api/model"
+	"github.com/onflow/flow-account-api/storage"
+)
+
+var (
+	// accountsBucket maps addresses to encoded accounts.
+	accountsBucket = []byte("accounts")
+	// publicKeysBucket indexes accounts by public key, keyed by public key and address.
+	publicKeysBucket = []byte("public_keys")
+	// createdBucket indexes accounts in creation order, keyed by creation time and address.
+	createdBucket = []byte("created")
+	// auditEventsBucket maps big-endian event IDs to encoded audit events, in the order they were recorded.
+	auditEventsBucket = []byte("audit_events")
+	// webhookDeliveriesBucket maps big-endian delivery IDs to encoded webhook deliveries.
+	webhookDeliveriesBucket = []byte("webhook_deliveries")
+	// webhookPendingBucket indexes the IDs of pending webhook deliveries, so that claims skip finished ones.
+	webhookPendingBucket = []byte("webhook_pending")
+)
+
+const keySeparator = "/"
+
+type Store struct {
+	path string
+	db   *bolt.DB
+}
+
+// NewStore creates a store backed by the database file at path.
+// The file is not opened until Open is called.
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+// Open opens the database file, creating it and its buckets if they do not exist.
+//
+// The store cannot be used until Open has returned.
+func (s *Store) Open() error {
+	db, err := bolt.Open(s.path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{
+			accountsBucket,
+			publicKeysBucket,
+			createdBucket,
+			auditEventsBucket,
+			webhookDeliveriesBucket,
+			webhookPendingBucket,
+		} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return err
+	}
+
+	s.db = db
+
+	return nil
+}
+
+// Close closes the database file.
+func (s *Store) Close() error {
+	// the file is not open if the store failed to open
+	if s.db == nil {
+		return nil
+	}
+
+	return s.db.Close()
+}
+
+// errNotOpen is returned by every operation made before the store has been opened.
+var errNotOpen = errors.New("database file is not open")
+
+// ready returns errNotOpen if the store has not been opened, or the error of a done context.
+func (s *Store) ready(ctx context.Context) error {
+	if s.db == nil {
+		return errNotOpen
+	}
+
+	return ctx.Err()
+}
+
+func (s *Store) InsertAccount(ctx context.Context, account *model.Account, event *model.AuditEvent) error {
+	if err := s.ready(ctx); err != nil {
+		return err
+	}
+
+	address, err := storage.NormalizeAddress(account.Address)
+	if err != nil {
+		return err
+	}
+
+	account.Address = address
+
+	if account.CreatedAt.IsZero() {
+		account.CreatedAt = time.Now().UTC()
+	}
+
+	if account.UpdatedAt.IsZero() {
+		account.UpdatedAt = account.CreatedAt
+	}
+
+	if event != nil && event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now().UTC()
+	}
+
+	for _, publicKey := range account.PublicKeys {
+		publicKey.AccountAddress = account.Address
+	}
+
+	value, err := encode(account)
+	if err != nil {
+		return err
+	}
+
+	// the transaction is rolled back if any of the checks below fail
+	return s.db.Update(func(tx *bolt.Tx) error {
+		accounts := tx.Bucket(accountsBucket)
+
+		if accounts.Get([]byte(account.Address)) != nil {
+			return storage.ErrExists
+		}
+
+		err := accounts.Put([]byte(account.Address), value)
+		if err != nil {
+			return err
+		}
+
+		publicKeys := tx.Bucket(publicKeysBucket)
+
+		for _, publicKey := range account.PublicKeys {
+			key := publicKeyIndexKey(publicKey.PublicKey, account.Address)
+
+			if publicKeys.Get(key) != nil {
+				return storage.ErrExists
+			}
+
+			err := publicKeys.Put(key, []byte{})
+			if err != nil {
+				return err
+			}
+		}
+
+		err = tx.Bucket(createdBucket).Put(createdIndexKey(account.CreatedAt, account.Address), []byte{})
+		if err != nil {
+			return err
+		}
+
+		if event == nil {
+			return nil
+		}
+
+		return putAuditEvent(tx, event)
+	})
+}
+
+func (s *Store) GetAccountByPublicKey(ctx context.Context, publicKey string, account *model.Account) error {
+	accounts, err := s.GetAccountsByPublicKey(ctx, publicKey)
+	if err != nil {
+		return err
+	}
+
+	*account = *accounts[0]
+
+	return nil
+}
+
+func (s *Store) GetAccountsByPublicKey(ctx context.Context, publicKey string) ([]*model.Account, error) {
+	if err := s.ready(ctx); err != nil {
+		return nil, err
+	}
+
+	var accounts []*model.Account
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		accounts, err = getAccountsByPublicKey(tx, publicKey)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(accounts) == 0 {
+		return nil, storage.ErrNotFound
+	}
+
+	return accounts, nil
+}
+
+func (s *Store) GetAccountsByPublicKeys(ctx context.Context, publicKeys []string) (map[string][]*model.Account, error) {
+	if err := s.ready(ctx); err != nil {
+		return nil, err
+	}
+
+	accounts := make(map[string][]*model.Account)
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		for _, publicKey := range publicKeys {
+			matches, err := getAccountsByPublicKey(tx, publicKey)
+			if err != nil {
+				return err
+			}
+
+			if len(matches) > 0 {
+				accounts[publicKey] = matches
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return accounts, nil
+}
+
+func (s *Store) GetAccountByAddress(ctx context.Context, address string, account *model.Account) error {
+	if err := s.ready(ctx); err != nil {
+		return err
+	}
+
+	address, err := storage.NormalizeAddress(address)
+	if err != nil {
+		return err
+	}
+
+	return s.db.View(func(tx *bolt.Tx) error {
+		a, err := getAccount(tx, address)
+		if err != nil {
+			return err
+		}
+
+		*account = *a
+
+		return nil
+	})
+}
+
+func (s *Store) GetAccountCount(ctx context.Context) (int, error) {
+	if err := s.ready(ctx); err != nil {
+		return 0, err
+	}
+
+	var count int
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		count = tx.Bucket(accountsBucket).Stats().KeyN
+		return nil
+	})
+
+	return count, err
+}
+
+// Ping checks that the database file is still open.
+func (s *Store) Ping(ctx context.Context) error {
+	if err := s.ready(ctx); err != nil {
+		return err
+	}
+
+	return s.db.View(func(tx *bolt.Tx) error {
+		return nil
+	})
+}
+
+func (s *Store) ListAccounts(
+	ctx context.Context,
+	filter storage.AccountFilter,
+	cursor storage.Cursor,
+	limit int,
+) (*storage.AccountPage, error) {
+	if err := s.ready(ctx); err != nil {
+		return nil, err
+	}
+
+	accounts := make([]*model.Account, 0)
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(createdBucket).Cursor()
+
+		var key []byte
+
+		if cursor.IsZero() {
+			key, _ = c.First()
+		} else {
+			start := createdIndexKey(cursor.CreatedAt, cursor.Address)
+
+			key, _ = c.Seek(start)
+			if bytes.Equal(key, start) {
+				key, _ = c.Next()
+			}
+		}
+
+		// fetch one extra account to find out whether there is another page
+		for ; key != nil && len(accounts) <= limit; key, _ = c.Next() {
+			account, err := getAccount(tx, string(key[8:]))
+			if err != nil {
+				return err
+			}
+
+			if filter.Matches(account) {
+				accounts = append(accounts, account)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	page := &storage.AccountPage{Accounts: accounts}
+
+	if len(accounts) > limit {
+		page.Accounts = accounts[:limit]
+		page.NextCursor = storage.CursorAfter(page.Accounts[limit-1]).Encode()
+	}
+
+	return page, nil
+}
+
+func (s *Store) InsertAuditEvent(ctx context.Context, event *model.AuditEvent) error {
+	if err := s.ready(ctx); err != nil {
+		return err
+	}
+
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now().UTC()
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return putAuditEvent(tx, event)
+	})
+}
+
+// putAuditEvent assigns an event its ID and appends it to the audit log.
+func putAuditEvent(tx *bolt.Tx, event *model.AuditEvent) error {
+	events := tx.Bucket(auditEventsBucket)
+
+	id, err := events.NextSequence()
+	if err != nil {
+		return err
+	}
+
+	event.ID = int64(id)
+
+	value, err := encode(event)
+	if err != nil {
+		return err
+	}
+
+	return events.Put(idKey(event.ID), value)
+}
+
+func (s *Store) ListAuditEvents(
+	ctx context.Context,
+	filter storage.AuditEventFilter,
+	afterID int64,
+	limit int,
+) (*storage.AuditEventPage, error) {
+	if err := s.ready(ctx); err != nil {
+		return nil, err
+	}
+
+	events := make([]*model.AuditEvent, 0)
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(auditEventsBucket).Cursor()
+
+		// fetch one extra event to find out whether there is another page
+		for _, value := c.Seek(idKey(afterID + 1)); value != nil && len(events) <= limit; _, value = c.Next() {
+			var event model.AuditEvent
+
+			err := decode(value, &event)
+			if err != nil {
+				return err
+			}
+
+			if filter.Matches(&event) {
+				events = append(events, &event)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return storage.NewAuditEventPage(events, limit), nil
+}
+
+func (s *Store) InsertWebhookDeliveries(ctx context.Context, deliveries []*model.WebhookDelivery) error {
+	if err := s.ready(ctx); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(webhookDeliveriesBucket)
+
+		for _, delivery := range deliveries {
+			if delivery.CreatedAt.IsZero() {
+				delivery.CreatedAt = now
+			}
+
+			id, err := bucket.NextSequence()
+			if err != nil {
+				return err
+			}
+
+			delivery.ID = int64(id)
+
+			err = putWebhookDelivery(tx, delivery)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (s *Store) ClaimWebhookDeliveries(
+	ctx context.Context,
+	now time.Time,
+	lease time.Duration,
+	limit int,
+) ([]*model.WebhookDelivery, error) {
+	if err := s.ready(ctx); err != nil {
+		return nil, err
+	}
+
+	claimed := make([]*model.WebhookDelivery, 0)
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		c := tx.Bucket(webhookPendingBucket).Cursor()
+
+		var due []*model.WebhookDelivery
+
+		for key, _ := c.First(); key != nil && len(due) < limit; key, _ = c.Next() {
+			delivery, err := getWebhookDelivery(tx, key)
+			if err != nil {
+				return err
+			}
+
+			if !delivery.NextAttemptAt.After(now) {
+				due = append(due, delivery)
+			}
+		}
+
+		// deliveries are updated after iterating, since bolt cursors are invalidated by writes
+		for _, delivery := range due {
+			delivery.NextAttemptAt = now.Add(lease)
+
+			err := putWebhookDelivery(tx, delivery)
+			if err != nil {
+				return err
+			}
+
+			claimed = append(claimed, delivery)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return claimed, nil
+}
+
+func (s *Store) UpdateWebhookDelivery(ctx context.Context, delivery *model.WebhookDelivery) error {
+	if err := s.ready(ctx); err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(webhookDeliveriesBucket).Get(idKey(delivery.ID)) == nil {
+			return storage.ErrNotFound
+		}
+
+		return putWebhookDelivery(tx, delivery)
+	})
+}
+
+func (s *Store) ListWebhookDeliveries(
+	ctx context.Context,
+	status string,
+	afterID int64,
+	limit int,
+) (*storage.WebhookDeliveryPage, error) {
+	if err := s.ready(ctx); err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]*model.WebhookDelivery, 0)
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(webhookDeliveriesBucket).Cursor()
+
+		// fetch one extra delivery to find out whether there is another page
+		for _, value := c.Seek(idKey(afterID + 1)); value != nil && len(deliveries) <= limit; _, value = c.Next() {
+			var delivery model.WebhookDelivery
+
+			err := decode(value, &delivery)
+			if err != nil {
+				return err
+			}
+
+			if delivery.Status == status {
+				deliveries = append(deliveries, &delivery)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return storage.NewWebhookDeliveryPage(deliveries, limit), nil
+}
+
+func getWebhookDelivery(tx *bolt.Tx, key []byte) (*model.WebhookDelivery, error) {
+	value := tx.Bucket(webhookDeliveriesBucket).Get(key)
+	if value == nil {
+		return nil, storage.ErrNotFound
+	}
+
+	var delivery model.WebhookDelivery
+
+	err := decode(value, &delivery)
+	if err != nil {
+		return nil, err
+	}
+
+	return &delivery, nil
+}
+
+// putWebhookDelivery stores a delivery and keeps the pending index in step with its status.
+func putWebhookDelivery(tx *bolt.Tx, delivery *model.WebhookDelivery) error {
+	key := idKey(delivery.ID)
+
+	value, err := encode(delivery)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Bucket(webhookDeliveriesBucket).Put(key, value)
+	if err != nil {
+		return err
+	}
+
+	if delivery.Status == model.WebhookDeliveryPending {
+		return tx.Bucket(webhookPendingBucket).Put(key, []byte{})
+	}
+
+	return tx.Bucket(webhookPendingBucket).Delete(key)
+}
+
+func getAccount(tx *bolt.Tx, address string) (*model.Account, error) {
+	value := tx.Bucket(accountsBucket).Get([]byte(address))
+	if value == nil {
+		return nil, storage.ErrNotFound
+	}
+
+	return decodeAccount(value)
+}
+
+// getAccountsByPublicKey returns the accounts associated with a public key, ordered by address.
+func getAccountsByPublicKey(tx *bolt.Tx, publicKey string) ([]*model.Account, error) {
+	prefix := publicKeyIndexKey(publicKey, "")
+
+	accounts := make([]*model.Account, 0)
+
+	c := tx.Bucket(publicKeysBucket).Cursor()
+
+	for key, _ := c.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, _ = c.Next() {
+		account, err := getAccount(tx, string(key[len(prefix):]))
+		if err != nil {
+			return nil, err
+		}
+
+		accounts = append(accounts, account)
+	}
+
+	return accounts, nil
+}
+
+func publicKeyIndexKey(publicKey, address string) []byte {
+	return []byte(publicKey + keySeparator + address)
+}
+
+// createdIndexKey orders accounts by creation time and then by address.
+func createdIndexKey(createdAt time.Time, address string) []byte {
+	key := make([]byte, 8, 8+len(address))
+	binary.BigEndian.PutUint64(key, uint64(createdAt.UnixNano()))
+	return append(key, address...)
+}
+
+// idKey orders records by their sequential ID.
+func idKey(id int64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(id))
+	return key
+}
+
+func encode(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+
+	err := gob.NewEncoder(&buf).Encode(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func decodeAccount(value []byte) (*model.Account, error) {
+	var account model.Account
+
+	err := decode(value, &account)
+	if err != nil {
+		return nil, err
+	}
+
+	return &account, nil
+}
+
+func decode(value []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(value)).Decode(v)
+}
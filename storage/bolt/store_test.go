@@ -0,0 +1,26 @@
+package bolt
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/onflow/flow-account-api/storage"
+	"github.com/onflow/flow-account-api/storage/storetest"
+)
+
+func TestStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) storage.Store {
+		store := NewStore(filepath.Join(t.TempDir(), "accounts.db"))
+
+		err := store.Open()
+		if err != nil {
+			t.Fatalf("failed to open store: %v", err)
+		}
+
+		t.Cleanup(func() {
+			_ = store.Close()
+		})
+
+		return store
+	})
+}
@@ -0,0 +1,160 @@
+package storage
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/onflow/flow-account-api/model"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// AccountFilter restricts the accounts returned by ListAccounts.
+//
+// Zero values match all accounts.
+type AccountFilter struct {
+	// CreatedAfter matches accounts created at or after this time.
+	CreatedAfter time.Time
+	// CreatedBefore matches accounts created before this time.
+	CreatedBefore time.Time
+	// SigAlgo matches accounts with at least one key using this signature algorithm.
+	SigAlgo string
+	// HashAlgo matches accounts with at least one key using this hash algorithm.
+	HashAlgo string
+	// ClientID matches accounts created on behalf of this API client.
+	ClientID string
+	// HasLockedAddress matches accounts with or without a locked address.
+	HasLockedAddress *bool
+}
+
+// Matches reports whether an account satisfies the filter.
+func (f AccountFilter) Matches(account *model.Account) bool {
+	if !f.CreatedAfter.IsZero() && account.CreatedAt.Before(f.CreatedAfter) {
+		return false
+	}
+
+	if !f.CreatedBefore.IsZero() && !account.CreatedAt.Before(f.CreatedBefore) {
+		return false
+	}
+
+	if f.ClientID != "" && account.ClientID != f.ClientID {
+		return false
+	}
+
+	if f.HasLockedAddress != nil && (account.LockedAddress != "") != *f.HasLockedAddress {
+		return false
+	}
+
+	if f.SigAlgo == "" && f.HashAlgo == "" {
+		return true
+	}
+
+	for _, publicKey := range account.PublicKeys {
+		if (f.SigAlgo == "" || publicKey.SigAlgo == f.SigAlgo) &&
+			(f.HashAlgo == "" || publicKey.HashAlgo == f.HashAlgo) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AccountPage is a page of accounts ordered by creation time and then by address.
+type AccountPage struct {
+	Accounts []*model.Account `json:"accounts"`
+	// NextCursor resumes the listing after the last account in this page.
+	// It is empty if there are no more accounts.
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// Cursor is a position in the ordering of accounts returned by ListAccounts.
+type Cursor struct {
+	CreatedAt time.Time
+	Address   string
+}
+
+// CursorAfter returns the cursor positioned after the given account.
+func CursorAfter(account *model.Account) Cursor {
+	return Cursor{
+		CreatedAt: account.CreatedAt,
+		Address:   account.Address,
+	}
+}
+
+// IsZero reports whether the cursor points to the start of the listing.
+func (c Cursor) IsZero() bool {
+	return c.CreatedAt.IsZero() && c.Address == ""
+}
+
+// Before reports whether the cursor is positioned before the given account.
+func (c Cursor) Before(account *model.Account) bool {
+	if account.CreatedAt.Equal(c.CreatedAt) {
+		return account.Address > c.Address
+	}
+
+	return account.CreatedAt.After(c.CreatedAt)
+}
+
+// Encode returns the opaque string representation of the cursor.
+func (c Cursor) Encode() string {
+	raw := fmt.Sprintf("%d:%s", c.CreatedAt.UnixNano(), c.Address)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor parses a cursor previously returned by Cursor.Encode.
+// An empty string decodes to the zero cursor.
+func DecodeCursor(encoded string) (Cursor, error) {
+	if encoded == "" {
+		return Cursor{}, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	parts := strings.SplitN(string(raw), ":", 2)
+	if len(parts) != 2 {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	address, err := NormalizeAddress(parts[1])
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	return Cursor{
+		CreatedAt: time.Unix(0, nanos).UTC(),
+		Address:   address,
+	}, nil
+}
+
+// EncodeIDCursor returns the cursor positioned after the record with the given ID,
+// for listings ordered by a sequential ID.
+func EncodeIDCursor(id int64) string {
+	return strconv.FormatInt(id, 10)
+}
+
+// DecodeIDCursor parses a cursor previously returned by EncodeIDCursor into the ID of
+// the last record already returned. An empty string decodes to zero.
+func DecodeIDCursor(encoded string) (int64, error) {
+	if encoded == "" {
+		return 0, nil
+	}
+
+	id, err := strconv.ParseInt(encoded, 10, 64)
+	if err != nil || id < 0 {
+		return 0, ErrInvalidCursor
+	}
+
+	return id, nil
+}
@@ -0,0 +1,226 @@
+package memory
+
+import (
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/onflow/flow-account-api/model"
+)
+
+// snapshot is the encoded form of a store.
+type snapshot struct {
+	Accounts          []model.Account
+	AuditEvents       []model.AuditEvent
+	WebhookDeliveries []model.WebhookDelivery
+}
+
+// Snapshot writes every account, audit event and webhook delivery in the store to w.
+func (s *Store) Snapshot(w io.Writer) error {
+	s.mut.RLock()
+
+	snap := snapshot{
+		Accounts: make([]model.Account, 0, len(s.accounts)),
+	}
+
+	for _, account := range s.accounts {
+		snap.Accounts = append(snap.Accounts, account)
+	}
+
+	snap.AuditEvents = append([]model.AuditEvent(nil), s.auditEvents...)
+	snap.WebhookDeliveries = append([]model.WebhookDelivery(nil), s.webhookDeliveries...)
+
+	s.mut.RUnlock()
+
+	// write accounts in creation order so that snapshots of the same store are identical,
+	// breaking ties by address since accounts can be created in the same instant
+	sort.Slice(snap.Accounts, func(i, j int) bool {
+		a, b := &snap.Accounts[i], &snap.Accounts[j]
+
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+
+		return a.Address < b.Address
+	})
+
+	return gob.NewEncoder(w).Encode(&snap)
+}
+
+// Restore replaces the contents of the store with a snapshot read from r.
+//
+// The store is left unchanged if the snapshot cannot be read.
+func (s *Store) Restore(r io.Reader) error {
+	var snap snapshot
+
+	err := gob.NewDecoder(r).Decode(&snap)
+	if err != nil {
+		return fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+
+	restored := NewStore()
+
+	for i := range snap.Accounts {
+		err := restored.InsertAccount(context.Background(), &snap.Accounts[i], nil)
+		if err != nil {
+			return fmt.Errorf("failed to restore account %s: %w", snap.Accounts[i].Address, err)
+		}
+	}
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	s.accounts = restored.accounts
+	s.publicKeysToAddresses = restored.publicKeysToAddresses
+	s.auditEvents = snap.AuditEvents
+	s.webhookDeliveries = snap.WebhookDeliveries
+	s.version++
+
+	return nil
+}
+
+// SaveSnapshot writes a snapshot of the store to the file at path.
+//
+// The snapshot is written to a temporary file first and then renamed,
+// so an interrupted save never corrupts the previous snapshot.
+func (s *Store) SaveSnapshot(path string) error {
+	return writeFileAtomic(path, s.Snapshot)
+}
+
+// writeFileAtomic replaces the file at path with the output of write, or leaves it unchanged if write fails.
+func writeFileAtomic(path string, write func(w io.Writer) error) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(f.Name())
+
+	err = write(f)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	err = f.Sync()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
+
+// LoadSnapshot restores the store from the snapshot file at path.
+//
+// It does nothing if the file does not exist yet.
+func (s *Store) LoadSnapshot(path string) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	return s.Restore(f)
+}
+
+func (s *Store) currentVersion() uint64 {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+	return s.version
+}
+
+// Snapshotter is a routine that periodically saves snapshots of a store to a file,
+// and saves a final snapshot when it is stopped.
+type Snapshotter struct {
+	store    *Store
+	path     string
+	interval time.Duration
+	logger   zerolog.Logger
+	saved    uint64
+	done     chan bool
+	stopped  chan bool
+}
+
+// NewSnapshotter creates a snapshotter for a store that has been restored from path.
+func NewSnapshotter(store *Store, path string, interval time.Duration, logger zerolog.Logger) *Snapshotter {
+	return &Snapshotter{
+		store:    store,
+		path:     path,
+		interval: interval,
+		logger:   logger,
+		saved:    store.currentVersion(),
+		done:     make(chan bool, 1),
+		stopped:  make(chan bool, 1),
+	}
+}
+
+func (s *Snapshotter) Start() error {
+	defer func() {
+		s.stopped <- true
+	}()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			err := s.save()
+			if err != nil {
+				// keep running, the next snapshot may succeed
+				s.logger.Error().Err(err).Str("path", s.path).Msg("failed to save snapshot")
+			}
+		case <-s.done:
+			err := s.save()
+			if err != nil {
+				s.logger.Error().Err(err).Str("path", s.path).Msg("failed to save final snapshot")
+				return err
+			}
+
+			return nil
+		}
+	}
+}
+
+// Stop saves a final snapshot and waits for it to be written.
+func (s *Snapshotter) Stop() {
+	s.done <- true
+	<-s.stopped
+}
+
+func (s *Snapshotter) save() error {
+	version := s.store.currentVersion()
+	if version == s.saved {
+		return nil
+	}
+
+	err := s.store.SaveSnapshot(s.path)
+	if err != nil {
+		return err
+	}
+
+	s.saved = version
+
+	s.logger.Debug().Str("path", s.path).Msg("saved snapshot")
+
+	return nil
+}
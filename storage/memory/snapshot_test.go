@@ -0,0 +1,290 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/onflow/flow-account-api/model"
+	"github.com/onflow/flow-account-api/storage"
+)
+
+// newSnapshotTestStore returns a store holding accounts, a public key shared by two of them,
+// audit events and webhook deliveries.
+func newSnapshotTestStore(t *testing.T) *Store {
+	t.Helper()
+
+	ctx := context.Background()
+	store := NewStore()
+
+	// accounts created in the same instant, so that only their addresses order them in a snapshot
+	createdAt := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	accounts := []*model.Account{
+		{
+			Address:   "0000000000000003",
+			CreatedAt: createdAt,
+			PublicKeys: []*model.AccountPublicKey{
+				{PublicKey: "aa", SigAlgo: "ECDSA_P256", HashAlgo: "SHA3_256"},
+				{PublicKey: "bb", SigAlgo: "ECDSA_secp256k1", HashAlgo: "SHA2_256"},
+			},
+		},
+		{
+			Address:   "0000000000000001",
+			CreatedAt: createdAt,
+			PublicKeys: []*model.AccountPublicKey{
+				{PublicKey: "aa", SigAlgo: "ECDSA_P256", HashAlgo: "SHA3_256"},
+			},
+		},
+		{
+			Address:   "0000000000000002",
+			CreatedAt: createdAt.Add(-time.Hour),
+			PublicKeys: []*model.AccountPublicKey{
+				{PublicKey: "cc", SigAlgo: "ECDSA_P256", HashAlgo: "SHA3_256"},
+			},
+		},
+	}
+
+	for _, account := range accounts {
+		event := &model.AuditEvent{
+			CreatedAt: createdAt,
+			Type:      model.AuditEventAccountCreated,
+			Actor:     model.AuditActorClient,
+			Address:   account.Address,
+		}
+
+		if err := store.InsertAccount(ctx, account, event); err != nil {
+			t.Fatalf("failed to insert account: %v", err)
+		}
+	}
+
+	err := store.InsertAuditEvent(ctx, &model.AuditEvent{
+		CreatedAt:         createdAt,
+		Type:              model.AuditEventAccountCreationFailed,
+		Actor:             model.AuditActorClient,
+		RejectedPublicKey: "not a key",
+		Error:             "invalid public key",
+	})
+	if err != nil {
+		t.Fatalf("failed to insert audit event: %v", err)
+	}
+
+	err = store.InsertWebhookDeliveries(ctx, []*model.WebhookDelivery{
+		{
+			CreatedAt:     createdAt,
+			EventID:       "event-1",
+			EventType:     model.WebhookEventAccountCreated,
+			Subscription:  "backend",
+			Payload:       `{"id":"event-1"}`,
+			Status:        model.WebhookDeliveryDelivered,
+			Attempts:      1,
+			NextAttemptAt: createdAt,
+			LastAttemptAt: createdAt,
+		},
+		{
+			CreatedAt:     createdAt,
+			EventID:       "event-2",
+			EventType:     model.WebhookEventAccountCreationFailed,
+			Subscription:  "backend",
+			Payload:       `{"id":"event-2"}`,
+			Status:        model.WebhookDeliveryPending,
+			Attempts:      2,
+			NextAttemptAt: createdAt.Add(time.Minute),
+			LastAttemptAt: createdAt,
+			LastError:     "subscriber responded with status 503",
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to queue webhook deliveries: %v", err)
+	}
+
+	return store
+}
+
+// storeContents reads everything the store API exposes, for comparing stores.
+func storeContents(t *testing.T, store *Store) map[string]interface{} {
+	t.Helper()
+
+	ctx := context.Background()
+	contents := make(map[string]interface{})
+
+	accounts, err := store.ListAccounts(ctx, storage.AccountFilter{}, storage.Cursor{}, 100)
+	if err != nil {
+		t.Fatalf("failed to list accounts: %v", err)
+	}
+
+	contents["accounts"] = accounts.Accounts
+
+	keys, err := store.GetAccountsByPublicKeys(ctx, []string{"aa", "bb", "cc", "dd"})
+	if err != nil {
+		t.Fatalf("failed to look up accounts by public keys: %v", err)
+	}
+
+	contents["keys"] = keys
+
+	events, err := store.ListAuditEvents(ctx, storage.AuditEventFilter{}, 0, 100)
+	if err != nil {
+		t.Fatalf("failed to list audit events: %v", err)
+	}
+
+	contents["auditEvents"] = events.Events
+
+	for _, status := range []string{model.WebhookDeliveryPending, model.WebhookDeliveryDelivered, model.WebhookDeliveryDead} {
+		deliveries, err := store.ListWebhookDeliveries(ctx, status, 0, 100)
+		if err != nil {
+			t.Fatalf("failed to list %s webhook deliveries: %v", status, err)
+		}
+
+		contents[status] = deliveries.Deliveries
+	}
+
+	return contents
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	store := newSnapshotTestStore(t)
+
+	var buf bytes.Buffer
+	if err := store.Snapshot(&buf); err != nil {
+		t.Fatalf("failed to take snapshot: %v", err)
+	}
+
+	restored := NewStore()
+	if err := restored.Restore(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("failed to restore snapshot: %v", err)
+	}
+
+	want := storeContents(t, store)
+	got := storeContents(t, restored)
+
+	for name := range want {
+		if !reflect.DeepEqual(got[name], want[name]) {
+			t.Errorf("restored %s differ:\ngot  %#v\nwant %#v", name, got[name], want[name])
+		}
+	}
+
+	// the restored key index keeps the addresses sharing a key in order
+	accounts, err := restored.GetAccountsByPublicKey(context.Background(), "aa")
+	if err != nil || len(accounts) != 2 || accounts[0].Address != "0000000000000001" || accounts[1].Address != "0000000000000003" {
+		t.Errorf("got accounts %v and error %v for the shared key, want accounts 1 and 3", accounts, err)
+	}
+
+	// new records continue the IDs of the restored ones
+	event := &model.AuditEvent{Type: model.AuditEventAccountImported, Actor: model.AuditActorAdmin}
+	if err := restored.InsertAuditEvent(context.Background(), event); err != nil || event.ID != 5 {
+		t.Errorf("got audit event ID %d and error %v after the restore, want ID 5", event.ID, err)
+	}
+}
+
+func TestSnapshotIsDeterministic(t *testing.T) {
+	store := newSnapshotTestStore(t)
+
+	var first bytes.Buffer
+	if err := store.Snapshot(&first); err != nil {
+		t.Fatalf("failed to take snapshot: %v", err)
+	}
+
+	// accounts are held in a map, so take enough snapshots for its iteration order to vary
+	for i := 0; i < 20; i++ {
+		var buf bytes.Buffer
+		if err := store.Snapshot(&buf); err != nil {
+			t.Fatalf("failed to take snapshot: %v", err)
+		}
+
+		if !bytes.Equal(buf.Bytes(), first.Bytes()) {
+			t.Fatal("snapshots of an unchanged store differ")
+		}
+	}
+}
+
+func TestRestoreInvalidSnapshotLeavesStoreUnchanged(t *testing.T) {
+	store := newSnapshotTestStore(t)
+	want := storeContents(t, store)
+
+	err := store.Restore(bytes.NewReader([]byte("not a snapshot")))
+	if err == nil {
+		t.Fatal("restored an invalid snapshot")
+	}
+
+	if got := storeContents(t, store); !reflect.DeepEqual(got, want) {
+		t.Error("a failed restore changed the store")
+	}
+}
+
+func TestSaveAndLoadSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "accounts.snapshot")
+
+	loaded := NewStore()
+	if err := loaded.LoadSnapshot(path); err != nil {
+		t.Fatalf("failed to load a snapshot that does not exist yet: %v", err)
+	}
+
+	store := newSnapshotTestStore(t)
+	if err := store.SaveSnapshot(path); err != nil {
+		t.Fatalf("failed to save snapshot: %v", err)
+	}
+
+	if err := loaded.LoadSnapshot(path); err != nil {
+		t.Fatalf("failed to load snapshot: %v", err)
+	}
+
+	if got, want := storeContents(t, loaded), storeContents(t, store); !reflect.DeepEqual(got, want) {
+		t.Error("the loaded store differs from the saved one")
+	}
+}
+
+func TestWriteFileAtomicKeepsPreviousFileOnFailure(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "accounts.snapshot")
+
+	if err := ioutil.WriteFile(path, []byte("previous snapshot"), 0600); err != nil {
+		t.Fatalf("failed to write previous snapshot: %v", err)
+	}
+
+	errWrite := errors.New("disk full")
+
+	err := writeFileAtomic(path, func(w io.Writer) error {
+		_, _ = w.Write([]byte("partial"))
+		return errWrite
+	})
+	if !errors.Is(err, errWrite) {
+		t.Fatalf("got error %v, want %v", err, errWrite)
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil || string(contents) != "previous snapshot" {
+		t.Errorf("got snapshot %q and error %v after a failed write, want the previous snapshot", contents, err)
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to read snapshot directory: %v", err)
+	}
+
+	if len(files) != 1 {
+		var names []string
+		for _, f := range files {
+			names = append(names, f.Name())
+		}
+
+		t.Errorf("got files %v after a failed write, want the temporary file to be removed", names)
+	}
+
+	err = writeFileAtomic(path, func(w io.Writer) error {
+		_, err := w.Write([]byte("next snapshot"))
+		return err
+	})
+	if err != nil {
+		t.Fatalf("failed to write snapshot: %v", err)
+	}
+
+	if contents, _ := ioutil.ReadFile(path); string(contents) != "next snapshot" {
+		t.Errorf("got snapshot %q, want the next snapshot", contents)
+	}
+}
@@ -1,57 +1,190 @@
 package memory
 
 import (
+	"context"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/onflow/flow-account-api/model"
 	"github.com/onflow/flow-account-api/storage"
 )
 
 type Store struct {
-	mut                 sync.RWMutex
-	accounts            map[string]model.Account
-	publicKeysToAddress map[string]string
+	mut                   sync.RWMutex
+	accounts              map[string]model.Account
+	publicKeysToAddresses map[string][]string
+	// auditEvents are kept in the order they were recorded, so each event's ID is its position plus one
+	auditEvents []model.AuditEvent
+	// webhookDeliveries are kept in the order they were queued, so each delivery's ID is its position plus one
+	webhookDeliveries []model.WebhookDelivery
+	// version is incremented on every change, so that snapshots can be skipped when nothing changed
+	version uint64
 }
 
 func NewStore() *Store {
 	return &Store{
-		accounts:            make(map[string]model.Account),
-		publicKeysToAddress: make(map[string]string),
+		accounts:              make(map[string]model.Account),
+		publicKeysToAddresses: make(map[string][]string),
 	}
 }
 
-func (s *Store) InsertAccount(account *model.Account) error {
+// Open does nothing, since the store holds everything in memory.
+// It lets the store be managed like the other backends.
+func (s *Store) Open() error {
+	return nil
+}
+
+// Close does nothing; see Open.
+func (s *Store) Close() error {
+	return nil
+}
+
+func (s *Store) InsertAccount(_ context.Context, account *model.Account, event *model.AuditEvent) error {
+	address, err := storage.NormalizeAddress(account.Address)
+	if err != nil {
+		return err
+	}
+
+	account.Address = address
+
+	if account.CreatedAt.IsZero() {
+		account.CreatedAt = time.Now().UTC()
+	}
+
+	if account.UpdatedAt.IsZero() {
+		account.UpdatedAt = account.CreatedAt
+	}
+
+	if event != nil && event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now().UTC()
+	}
+
 	s.mut.Lock()
 	defer s.mut.Unlock()
 
+	// check for every conflict before mutating so that a failed insert leaves the store unchanged
 	_, ok := s.accounts[account.Address]
 	if ok {
 		return storage.ErrExists
 	}
 
-	s.accounts[account.Address] = *account
+	publicKeys := make(map[string]bool, len(account.PublicKeys))
 
 	for _, publicKey := range account.PublicKeys {
-		_, ok := s.publicKeysToAddress[publicKey.PublicKey]
-		if ok {
+		if publicKeys[publicKey.PublicKey] {
 			return storage.ErrExists
 		}
 
-		s.publicKeysToAddress[publicKey.PublicKey] = account.Address
+		if _, ok := s.findAddress(publicKey.PublicKey, account.Address); ok {
+			return storage.ErrExists
+		}
+
+		publicKeys[publicKey.PublicKey] = true
+	}
+
+	for _, publicKey := range account.PublicKeys {
+		publicKey.AccountAddress = account.Address
+
+		i, _ := s.findAddress(publicKey.PublicKey, account.Address)
+
+		// keep addresses sorted so that lookups return accounts in a stable order
+		addresses := append(s.publicKeysToAddresses[publicKey.PublicKey], "")
+		copy(addresses[i+1:], addresses[i:])
+		addresses[i] = account.Address
+
+		s.publicKeysToAddresses[publicKey.PublicKey] = addresses
 	}
 
+	s.accounts[account.Address] = *account
+
+	if event != nil {
+		s.appendAuditEvent(event)
+	}
+
+	s.version++
+
 	return nil
 }
 
-func (s *Store) GetAccountByPublicKey(publicKey string, account *model.Account) error {
+// findAddress returns the position of an address among the sorted addresses
+// associated with a public key, and whether it is present.
+func (s *Store) findAddress(publicKey, address string) (int, bool) {
+	addresses := s.publicKeysToAddresses[publicKey]
+
+	i := sort.SearchStrings(addresses, address)
+
+	return i, i < len(addresses) && addresses[i] == address
+}
+
+func (s *Store) GetAccountByPublicKey(_ context.Context, publicKey string, account *model.Account) error {
 	s.mut.RLock()
 	defer s.mut.RUnlock()
 
-	address, ok := s.publicKeysToAddress[publicKey]
-	if !ok {
+	accounts := s.getAccountsByPublicKey(publicKey)
+	if len(accounts) == 0 {
 		return storage.ErrNotFound
 	}
 
+	*account = *accounts[0]
+
+	return nil
+}
+
+func (s *Store) GetAccountsByPublicKey(_ context.Context, publicKey string) ([]*model.Account, error) {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	accounts := s.getAccountsByPublicKey(publicKey)
+	if len(accounts) == 0 {
+		return nil, storage.ErrNotFound
+	}
+
+	return accounts, nil
+}
+
+func (s *Store) GetAccountsByPublicKeys(_ context.Context, publicKeys []string) (map[string][]*model.Account, error) {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	accounts := make(map[string][]*model.Account)
+
+	for _, publicKey := range publicKeys {
+		matches := s.getAccountsByPublicKey(publicKey)
+		if len(matches) > 0 {
+			accounts[publicKey] = matches
+		}
+	}
+
+	return accounts, nil
+}
+
+func (s *Store) getAccountsByPublicKey(publicKey string) []*model.Account {
+	addresses := s.publicKeysToAddresses[publicKey]
+
+	accounts := make([]*model.Account, 0, len(addresses))
+
+	for _, address := range addresses {
+		a, ok := s.accounts[address]
+		if !ok {
+			continue
+		}
+
+		accounts = append(accounts, &a)
+	}
+
+	return accounts
+}
+
+func (s *Store) GetAccountByAddress(_ context.Context, address string, account *model.Account) error {
+	address, err := storage.NormalizeAddress(address)
+	if err != nil {
+		return err
+	}
+
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
 	a, ok := s.accounts[address]
 	if !ok {
 		return storage.ErrNotFound
@@ -62,9 +195,188 @@ func (s *Store) GetAccountByPublicKey(publicKey string, account *model.Account)
 	return nil
 }
 
-func (s *Store) GetAccountCount() (int, error) {
+func (s *Store) GetAccountCount(_ context.Context) (int, error) {
 	s.mut.RLock()
 	defer s.mut.RUnlock()
 
 	return len(s.accounts), nil
 }
+
+// Ping always succeeds, since the store is in memory.
+func (s *Store) Ping(_ context.Context) error {
+	return nil
+}
+
+func (s *Store) ListAccounts(_ context.Context, filter storage.AccountFilter, cursor storage.Cursor, limit int) (*storage.AccountPage, error) {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	matches := make([]*model.Account, 0)
+
+	for address := range s.accounts {
+		a := s.accounts[address]
+
+		if !cursor.IsZero() && !cursor.Before(&a) {
+			continue
+		}
+
+		if filter.Matches(&a) {
+			matches = append(matches, &a)
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].CreatedAt.Equal(matches[j].CreatedAt) {
+			return matches[i].Address < matches[j].Address
+		}
+
+		return matches[i].CreatedAt.Before(matches[j].CreatedAt)
+	})
+
+	page := &storage.AccountPage{Accounts: matches}
+
+	if len(matches) > limit {
+		page.Accounts = matches[:limit]
+		page.NextCursor = storage.CursorAfter(page.Accounts[limit-1]).Encode()
+	}
+
+	return page, nil
+}
+
+func (s *Store) InsertAuditEvent(_ context.Context, event *model.AuditEvent) error {
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now().UTC()
+	}
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	s.appendAuditEvent(event)
+	s.version++
+
+	return nil
+}
+
+// appendAuditEvent assigns an event its ID and appends it to the audit log.
+// The caller must hold the write lock.
+func (s *Store) appendAuditEvent(event *model.AuditEvent) {
+	event.ID = int64(len(s.auditEvents)) + 1
+
+	s.auditEvents = append(s.auditEvents, *event)
+}
+
+func (s *Store) ListAuditEvents(
+	_ context.Context,
+	filter storage.AuditEventFilter,
+	afterID int64,
+	limit int,
+) (*storage.AuditEventPage, error) {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	events := make([]*model.AuditEvent, 0)
+
+	// fetch one extra event to find out whether there is another page
+	for i := afterID; i < int64(len(s.auditEvents)) && len(events) <= limit; i++ {
+		e := s.auditEvents[i]
+
+		if filter.Matches(&e) {
+			events = append(events, &e)
+		}
+	}
+
+	return storage.NewAuditEventPage(events, limit), nil
+}
+
+func (s *Store) InsertWebhookDeliveries(_ context.Context, deliveries []*model.WebhookDelivery) error {
+	now := time.Now().UTC()
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	for _, delivery := range deliveries {
+		if delivery.CreatedAt.IsZero() {
+			delivery.CreatedAt = now
+		}
+
+		delivery.ID = int64(len(s.webhookDeliveries)) + 1
+
+		s.webhookDeliveries = append(s.webhookDeliveries, *delivery)
+	}
+
+	s.version++
+
+	return nil
+}
+
+func (s *Store) ClaimWebhookDeliveries(
+	_ context.Context,
+	now time.Time,
+	lease time.Duration,
+	limit int,
+) ([]*model.WebhookDelivery, error) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	claimed := make([]*model.WebhookDelivery, 0)
+
+	for i := range s.webhookDeliveries {
+		if len(claimed) == limit {
+			break
+		}
+
+		delivery := &s.webhookDeliveries[i]
+
+		if delivery.Status != model.WebhookDeliveryPending || delivery.NextAttemptAt.After(now) {
+			continue
+		}
+
+		delivery.NextAttemptAt = now.Add(lease)
+
+		d := *delivery
+		claimed = append(claimed, &d)
+	}
+
+	if len(claimed) > 0 {
+		s.version++
+	}
+
+	return claimed, nil
+}
+
+func (s *Store) UpdateWebhookDelivery(_ context.Context, delivery *model.WebhookDelivery) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if delivery.ID < 1 || delivery.ID > int64(len(s.webhookDeliveries)) {
+		return storage.ErrNotFound
+	}
+
+	s.webhookDeliveries[delivery.ID-1] = *delivery
+	s.version++
+
+	return nil
+}
+
+func (s *Store) ListWebhookDeliveries(
+	_ context.Context,
+	status string,
+	afterID int64,
+	limit int,
+) (*storage.WebhookDeliveryPage, error) {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	deliveries := make([]*model.WebhookDelivery, 0)
+
+	// fetch one extra delivery to find out whether there is another page
+	for i := afterID; i < int64(len(s.webhookDeliveries)) && len(deliveries) <= limit; i++ {
+		d := s.webhookDeliveries[i]
+
+		if d.Status == status {
+			deliveries = append(deliveries, &d)
+		}
+	}
+
+	return storage.NewWebhookDeliveryPage(deliveries, limit), nil
+}
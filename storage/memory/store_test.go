@@ -0,0 +1,14 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/onflow/flow-account-api/storage"
+	"github.com/onflow/flow-account-api/storage/storetest"
+)
+
+func TestStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) storage.Store {
+		return NewStore()
+	})
+}
@@ -0,0 +1,4 @@
+DROP INDEX public_keys_public_key_idx;
+
+ALTER TABLE public_keys DROP CONSTRAINT public_keys_pkey;
+ALTER TABLE public_keys ADD PRIMARY KEY (public_key);
@@ -0,0 +1,6 @@
+-- A public key may control more than one account,
+-- so it is only unique in combination with the account address.
+ALTER TABLE public_keys DROP CONSTRAINT public_keys_pkey;
+ALTER TABLE public_keys ADD PRIMARY KEY (account_address, public_key);
+
+CREATE INDEX public_keys_public_key_idx ON public_keys (public_key);
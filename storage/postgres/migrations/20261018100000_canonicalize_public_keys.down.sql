@@ -0,0 +1,2 @@
+-- The original encodings of canonicalized public keys are not retained,
+-- so this migration cannot be reversed.
@@ -0,0 +1,26 @@
+-- Public keys are stored as the lowercase hex of the raw curve point,
+-- without a 0x prefix or the 04 prefix of an uncompressed point.
+CREATE FUNCTION canonical_public_key_(public_key TEXT)
+RETURNS TEXT
+AS
+$$
+    SELECT CASE
+        WHEN length(k) = 130 AND k LIKE '04%' THEN substr(k, 3)
+        ELSE k
+    END
+    FROM (SELECT lower(regexp_replace(public_key, '^0x', '', 'i')) AS k) AS normalized;
+$$
+LANGUAGE SQL IMMUTABLE;
+
+-- Drop keys that are duplicates of another key on the same account once canonicalized.
+DELETE FROM public_keys a
+USING public_keys b
+WHERE a.account_address = b.account_address
+  AND canonical_public_key_(a.public_key) = canonical_public_key_(b.public_key)
+  AND a.ctid > b.ctid;
+
+UPDATE public_keys
+SET public_key = canonical_public_key_(public_key)
+WHERE public_key <> canonical_public_key_(public_key);
+
+DROP FUNCTION canonical_public_key_(TEXT);
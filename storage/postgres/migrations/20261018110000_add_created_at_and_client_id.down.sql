@@ -0,0 +1,5 @@
+DROP INDEX accounts_client_id_created_at_address_idx;
+DROP INDEX accounts_created_at_address_idx;
+
+ALTER TABLE accounts DROP COLUMN client_id;
+ALTER TABLE accounts DROP COLUMN created_at;
@@ -0,0 +1,6 @@
+ALTER TABLE accounts ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+ALTER TABLE accounts ADD COLUMN client_id TEXT;
+
+-- support paginating accounts in creation order, optionally per client
+CREATE INDEX accounts_created_at_address_idx ON accounts (created_at, address);
+CREATE INDEX accounts_client_id_created_at_address_idx ON accounts (client_id, created_at, address);
@@ -0,0 +1,8 @@
+DROP TRIGGER update_accounts_modified_ ON accounts;
+
+ALTER TABLE accounts DROP COLUMN client_user_agent;
+ALTER TABLE accounts DROP COLUMN client_ip;
+ALTER TABLE accounts DROP COLUMN creator_key_index;
+ALTER TABLE accounts DROP COLUMN network;
+ALTER TABLE accounts DROP COLUMN creation_block_height;
+ALTER TABLE accounts DROP COLUMN updated_at;
@@ -0,0 +1,11 @@
+ALTER TABLE accounts ADD COLUMN updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+ALTER TABLE accounts ADD COLUMN creation_block_height BIGINT;
+ALTER TABLE accounts ADD COLUMN network TEXT;
+ALTER TABLE accounts ADD COLUMN creator_key_index INTEGER;
+ALTER TABLE accounts ADD COLUMN client_ip TEXT;
+ALTER TABLE accounts ADD COLUMN client_user_agent TEXT;
+
+CREATE TRIGGER update_accounts_modified_
+    BEFORE UPDATE ON accounts
+    FOR EACH ROW
+    EXECUTE PROCEDURE update_row_modified_function_();
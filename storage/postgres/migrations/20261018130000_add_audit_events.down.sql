@@ -0,0 +1,3 @@
+DROP TRIGGER reject_audit_event_change_ ON audit_events;
+DROP FUNCTION reject_audit_event_change_function_();
+DROP TABLE audit_events;
@@ -0,0 +1,35 @@
+CREATE TABLE audit_events
+(
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    type TEXT NOT NULL,
+    actor TEXT NOT NULL,
+    address TEXT,
+    public_keys TEXT[],
+    rejected_public_key TEXT,
+    transaction_id TEXT,
+    request_id TEXT,
+    claimed_client_id TEXT,
+    client_ip TEXT,
+    client_user_agent TEXT,
+    error TEXT
+);
+
+CREATE INDEX audit_events_address_idx ON audit_events (address);
+CREATE INDEX audit_events_public_keys_idx ON audit_events USING GIN (public_keys);
+
+-- the audit log is append-only
+CREATE OR REPLACE FUNCTION reject_audit_event_change_function_()
+RETURNS TRIGGER
+AS
+$$
+BEGIN
+    RAISE EXCEPTION 'audit events cannot be changed';
+END;
+$$
+language 'plpgsql';
+
+CREATE TRIGGER reject_audit_event_change_
+    BEFORE UPDATE OR DELETE ON audit_events
+    FOR EACH ROW
+    EXECUTE PROCEDURE reject_audit_event_change_function_();
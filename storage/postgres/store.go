@@ -4,10 +4,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
+	gopg "github.com/go-pg/pg/v10"
 	"github.com/golang-migrate/migrate"
 	_ "github.com/golang-migrate/migrate/database/postgres"
-	_ "github.com/golang-migrate/migrate/source/file"
 	"github.com/rs/zerolog"
 
 	"github.com/onflow/flow-account-api/model"
@@ -21,77 +22,177 @@ type Store struct {
 	environment string
 	logger      zerolog.Logger
 	db          *pg.Database
-	done        chan bool
 }
 
+// NewStore creates a store for the configured database.
+// The database is not connected until Open is called.
 func NewStore(conf pg.Config, environment string, logger zerolog.Logger) (*Store, error) {
 	return &Store{
 		conf:        conf,
 		environment: environment,
 		logger:      logger,
-		done:        make(chan bool, 1),
 	}, nil
 }
 
-func (s *Store) Start() error {
+// Open connects to the database and prepares its schema according to the configured migration mode.
+//
+// The store cannot be used until Open has returned.
+func (s *Store) Open() error {
 	var err error
 	s.db, err = pg.NewDatabase(s.conf)
 	if err != nil {
 		return err
 	}
 
-	err = s.migrate()
-	if err != nil {
-		return fmt.Errorf("failed to migrate database: %w", err)
+	switch s.conf.MigrationMode {
+	case pg.MigrationModeAuto:
+		err = s.migrate()
+		if err != nil {
+			return fmt.Errorf("failed to migrate database: %w", err)
+		}
+
+		return s.verifySchema()
+	case pg.MigrationModeVerify:
+		return s.verifySchema()
+	case pg.MigrationModeOff:
+		s.logger.Warn().Msg("migrations are off, the database schema is not checked")
+		return nil
+	default:
+		return fmt.Errorf(
+			"unknown migration mode %q, must be one of %s, %s or %s",
+			s.conf.MigrationMode,
+			pg.MigrationModeAuto,
+			pg.MigrationModeVerify,
+			pg.MigrationModeOff,
+		)
+	}
+}
+
+// Close closes the database connection.
+func (s *Store) Close() error {
+	// the database is not connected if the store failed to open
+	if s.db == nil {
+		return nil
 	}
 
-	<-s.done
+	return s.db.Close()
+}
+
+// errNotConnected is returned by every query made before the store has been opened.
+var errNotConnected = errors.New("database is not connected")
+
+// connected returns errNotConnected if the store has not been opened.
+func (s Store) connected() error {
+	if s.db == nil {
+		return errNotConnected
+	}
 
 	return nil
 }
 
-func (s *Store) Stop() {
-	_ = s.db.Close()
-	s.done <- true
+// withQueryTimeout bounds the context of a query by the configured query timeout.
+func (s Store) withQueryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.conf.QueryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, s.conf.QueryTimeout)
 }
 
-func (s *Store) migrate() (err error) {
-	var v uint
+// migrate applies pending migrations while holding the migration lock,
+// so that replicas starting at the same time do not race to migrate.
+func (s *Store) migrate() error {
+	return s.db.WithMigrationLock(context.Background(), func() error {
+		var v uint
 
-	err = s.db.MigrateUp(context.Background(), &v)
-	if err != nil {
-		// https://github.com/mattes/migrate/issues/287
-		if err.Error() != migrate.ErrNoChange.Error() {
-			s.logger.
-				Error().
-				Err(err).
-				Msg("error performing migration")
+		err := s.db.MigrateUp(context.Background(), &v)
+		if err != nil {
+			// https://github.com/mattes/migrate/issues/287
+			if err.Error() != migrate.ErrNoChange.Error() {
+				s.logger.
+					Error().
+					Err(err).
+					Msg("error performing migration")
 
-			return err
-		} else if s.environment == wallet.EnvironmentTest {
-			s.logger.
-				Error().
-				Err(err).
-				Msg("expected to run migrations, but ran none, this is probably an error")
+				return err
+			} else if s.environment == wallet.EnvironmentTest {
+				s.logger.
+					Error().
+					Err(err).
+					Msg("expected to run migrations, but ran none, this is probably an error")
 
-			return err
+				return err
+			}
+
+			s.logger.Info().Msgf("already migrated to: %d", v)
+
+			return nil
 		}
 
-		s.logger.Info().Msgf("already migrated to: %d", v)
+		s.logger.Info().Msgf("Successfully migrated to version: %d", v)
 
 		return nil
+	})
+}
+
+// verifySchema fails if the database schema is behind the migrations embedded in the binary.
+func (s *Store) verifySchema() error {
+	var current uint
+
+	err := s.db.GetMigrationVersion(context.Background(), &current)
+	if err != nil {
+		return fmt.Errorf("failed to get database schema version: %w", err)
+	}
+
+	latest, err := s.db.LatestMigrationVersion()
+	if err != nil {
+		return err
+	}
+
+	if current < latest {
+		return fmt.Errorf(
+			"database schema is at version %d but version %d is required, run migrations before starting",
+			current,
+			latest,
+		)
 	}
 
-	s.logger.Info().Msgf("Successfully migrated to version: %d", v)
+	if current > latest {
+		// a newer release has migrated the database, which is expected during a rollback
+		s.logger.Warn().
+			Uint("version", current).
+			Uint("latest", latest).
+			Msg("database schema is newer than this release")
+	}
 
 	return nil
 }
 
-func (s Store) InsertAccount(account *model.Account) error {
-	ctx := context.Background()
+func (s Store) InsertAccount(ctx context.Context, account *model.Account, event *model.AuditEvent) error {
+	if err := s.connected(); err != nil {
+		return err
+	}
+
+	address, err := storage.NormalizeAddress(account.Address)
+	if err != nil {
+		return err
+	}
 
-	err := s.db.RunInTransaction(ctx, func(ctx context.Context) error {
-		_, err := s.db.Model(account).Insert()
+	account.Address = address
+
+	if account.CreatedAt.IsZero() {
+		account.CreatedAt = time.Now().UTC()
+	}
+
+	if event != nil && event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now().UTC()
+	}
+
+	ctx, cancel := s.withQueryTimeout(ctx)
+	defer cancel()
+
+	err = s.db.RunInTransaction(ctx, func(tx *gopg.Tx) error {
+		_, err := tx.ModelContext(ctx, account).Insert()
 		if err != nil {
 			return err
 		}
@@ -100,13 +201,19 @@ func (s Store) InsertAccount(account *model.Account) error {
 			// link public key to account
 			publicKey.AccountAddress = account.Address
 
-			_, err := s.db.Model(publicKey).Insert()
+			_, err := tx.ModelContext(ctx, publicKey).Insert()
 			if err != nil {
 				return err
 			}
 		}
 
-		return nil
+		if event == nil {
+			return nil
+		}
+
+		_, err = tx.ModelContext(ctx, event).Insert()
+
+		return err
 	})
 
 	if err != nil {
@@ -120,15 +227,123 @@ func (s Store) InsertAccount(account *model.Account) error {
 	return nil
 }
 
-func (s Store) GetAccountByPublicKey(publicKey string, account *model.Account) error {
-	ctx := context.Background()
+func (s Store) GetAccountByPublicKey(ctx context.Context, publicKey string, account *model.Account) error {
+	if err := s.connected(); err != nil {
+		return err
+	}
+
+	ctx, cancel := s.withQueryTimeout(ctx)
+	defer cancel()
+
+	err := s.db.RunInTransaction(ctx, func(tx *gopg.Tx) error {
+		return tx.ModelContext(ctx, account).
+			Relation("PublicKeys").
+			Where("account.address IN (SELECT account_address FROM public_keys WHERE public_key = ?)", publicKey).
+			Order("account.address ASC").
+			Limit(1).
+			Select()
+	})
+
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return storage.ErrNotFound
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (s Store) GetAccountsByPublicKey(ctx context.Context, publicKey string) ([]*model.Account, error) {
+	if err := s.connected(); err != nil {
+		return nil, err
+	}
+
+	var accounts []*model.Account
+
+	ctx, cancel := s.withQueryTimeout(ctx)
+	defer cancel()
+
+	err := s.db.RunInTransaction(ctx, func(tx *gopg.Tx) error {
+		return tx.ModelContext(ctx, &accounts).
+			Relation("PublicKeys").
+			Where("account.address IN (SELECT account_address FROM public_keys WHERE public_key = ?)", publicKey).
+			Order("account.address ASC").
+			Select()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(accounts) == 0 {
+		return nil, storage.ErrNotFound
+	}
+
+	return accounts, nil
+}
+
+func (s Store) GetAccountsByPublicKeys(ctx context.Context, publicKeys []string) (map[string][]*model.Account, error) {
+	if err := s.connected(); err != nil {
+		return nil, err
+	}
+
+	accounts := make(map[string][]*model.Account)
+
+	if len(publicKeys) == 0 {
+		return accounts, nil
+	}
+
+	var matches []*model.Account
+
+	ctx, cancel := s.withQueryTimeout(ctx)
+	defer cancel()
+
+	err := s.db.RunInTransaction(ctx, func(tx *gopg.Tx) error {
+		return tx.ModelContext(ctx, &matches).
+			Relation("PublicKeys").
+			Where("account.address IN (SELECT account_address FROM public_keys WHERE public_key IN (?))", gopg.In(publicKeys)).
+			Order("account.address ASC").
+			Select()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	requested := make(map[string]bool, len(publicKeys))
+	for _, publicKey := range publicKeys {
+		requested[publicKey] = true
+	}
+
+	// an account may hold keys other than the ones requested
+	for _, account := range matches {
+		for _, publicKey := range account.PublicKeys {
+			if requested[publicKey.PublicKey] {
+				accounts[publicKey.PublicKey] = append(accounts[publicKey.PublicKey], account)
+			}
+		}
+	}
+
+	return accounts, nil
+}
+
+func (s Store) GetAccountByAddress(ctx context.Context, address string, account *model.Account) error {
+	if err := s.connected(); err != nil {
+		return err
+	}
+
+	address, err := storage.NormalizeAddress(address)
+	if err != nil {
+		return err
+	}
 
-	err := s.db.RunInTransaction(ctx, func(ctx context.Context) error {
-		return s.db.Model(account).
-			Column("account.address").
+	ctx, cancel := s.withQueryTimeout(ctx)
+	defer cancel()
+
+	err = s.db.RunInTransaction(ctx, func(tx *gopg.Tx) error {
+		return tx.ModelContext(ctx, account).
 			Relation("PublicKeys").
-			Where("public_keys.public_key = ?", publicKey).
-			Join("JOIN public_keys ON account.address = public_keys.account_address").
+			Where("account.address = ?", address).
 			Select()
 	})
 
@@ -143,6 +358,263 @@ func (s Store) GetAccountByPublicKey(publicKey string, account *model.Account) e
 	return nil
 }
 
-func (s Store) GetAccountCount() (int, error) {
-	return s.db.Model(&model.Account{}).Count()
+func (s Store) GetAccountCount(ctx context.Context) (int, error) {
+	if err := s.connected(); err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := s.withQueryTimeout(ctx)
+	defer cancel()
+
+	return s.db.ModelContext(ctx, &model.Account{}).Count()
+}
+
+func (s Store) Ping(ctx context.Context) error {
+	if err := s.connected(); err != nil {
+		return err
+	}
+
+	ctx, cancel := s.withQueryTimeout(ctx)
+	defer cancel()
+
+	return s.db.Ping(ctx)
+}
+
+func (s Store) ListAccounts(ctx context.Context, filter storage.AccountFilter, cursor storage.Cursor, limit int) (*storage.AccountPage, error) {
+	if err := s.connected(); err != nil {
+		return nil, err
+	}
+
+	accounts := make([]*model.Account, 0)
+
+	ctx, cancel := s.withQueryTimeout(ctx)
+	defer cancel()
+
+	err := s.db.RunInTransaction(ctx, func(tx *gopg.Tx) error {
+		query := tx.ModelContext(ctx, &accounts).
+			Relation("PublicKeys")
+
+		if !cursor.IsZero() {
+			query.Where("(account.created_at, account.address) > (?, ?)", cursor.CreatedAt, cursor.Address)
+		}
+
+		if !filter.CreatedAfter.IsZero() {
+			query.Where("account.created_at >= ?", filter.CreatedAfter)
+		}
+
+		if !filter.CreatedBefore.IsZero() {
+			query.Where("account.created_at < ?", filter.CreatedBefore)
+		}
+
+		if filter.ClientID != "" {
+			query.Where("account.client_id = ?", filter.ClientID)
+		}
+
+		if filter.HasLockedAddress != nil {
+			if *filter.HasLockedAddress {
+				query.Where("COALESCE(account.locked_address, '') <> ''")
+			} else {
+				query.Where("COALESCE(account.locked_address, '') = ''")
+			}
+		}
+
+		if filter.SigAlgo != "" || filter.HashAlgo != "" {
+			query.Where(
+				"EXISTS (SELECT 1 FROM public_keys k WHERE k.account_address = account.address "+
+					"AND (? = '' OR k.sig_algo = ?) AND (? = '' OR k.hash_algo = ?))",
+				filter.SigAlgo, filter.SigAlgo, filter.HashAlgo, filter.HashAlgo,
+			)
+		}
+
+		// fetch one extra row to find out whether there is another page
+		return query.
+			Order("account.created_at ASC", "account.address ASC").
+			Limit(limit + 1).
+			Select()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	page := &storage.AccountPage{Accounts: accounts}
+
+	if len(accounts) > limit {
+		page.Accounts = accounts[:limit]
+		page.NextCursor = storage.CursorAfter(page.Accounts[limit-1]).Encode()
+	}
+
+	return page, nil
+}
+
+func (s Store) InsertAuditEvent(ctx context.Context, event *model.AuditEvent) error {
+	if err := s.connected(); err != nil {
+		return err
+	}
+
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now().UTC()
+	}
+
+	ctx, cancel := s.withQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := s.db.ModelContext(ctx, event).Insert()
+
+	return err
+}
+
+func (s Store) ListAuditEvents(
+	ctx context.Context,
+	filter storage.AuditEventFilter,
+	afterID int64,
+	limit int,
+) (*storage.AuditEventPage, error) {
+	if err := s.connected(); err != nil {
+		return nil, err
+	}
+
+	events := make([]*model.AuditEvent, 0)
+
+	ctx, cancel := s.withQueryTimeout(ctx)
+	defer cancel()
+
+	query := s.db.ModelContext(ctx, &events).
+		Where("id > ?", afterID)
+
+	if filter.Address != "" {
+		query.Where("address = ?", filter.Address)
+	}
+
+	if filter.PublicKey != "" {
+		query.Where("public_keys @> ARRAY[?]", filter.PublicKey)
+	}
+
+	if filter.Type != "" {
+		query.Where("type = ?", filter.Type)
+	}
+
+	// fetch one extra row to find out whether there is another page
+	err := query.
+		Order("id ASC").
+		Limit(limit + 1).
+		Select()
+	if err != nil {
+		return nil, err
+	}
+
+	return storage.NewAuditEventPage(events, limit), nil
+}
+
+func (s Store) InsertWebhookDeliveries(ctx context.Context, deliveries []*model.WebhookDelivery) error {
+	if err := s.connected(); err != nil {
+		return err
+	}
+
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	now := time.Now().UTC()
+
+	for _, delivery := range deliveries {
+		if delivery.CreatedAt.IsZero() {
+			delivery.CreatedAt = now
+		}
+	}
+
+	ctx, cancel := s.withQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := s.db.ModelContext(ctx, &deliveries).Insert()
+
+	return err
+}
+
+func (s Store) ClaimWebhookDeliveries(
+	ctx context.Context,
+	now time.Time,
+	lease time.Duration,
+	limit int,
+) ([]*model.WebhookDelivery, error) {
+	if err := s.connected(); err != nil {
+		return nil, err
+	}
+
+	claimed := make([]*model.WebhookDelivery, 0)
+
+	ctx, cancel := s.withQueryTimeout(ctx)
+	defer cancel()
+
+	// SKIP LOCKED lets dispatchers in other replicas claim different deliveries concurrently
+	_, err := s.db.QueryContext(
+		ctx,
+		&claimed,
+		`UPDATE webhook_deliveries SET next_attempt_at = ?
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = ? AND next_attempt_at <= ?
+			ORDER BY id
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		now.Add(lease),
+		model.WebhookDeliveryPending,
+		now,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return claimed, nil
+}
+
+func (s Store) UpdateWebhookDelivery(ctx context.Context, delivery *model.WebhookDelivery) error {
+	if err := s.connected(); err != nil {
+		return err
+	}
+
+	ctx, cancel := s.withQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ModelContext(ctx, delivery).WherePK().Update()
+	if err != nil {
+		return err
+	}
+
+	if res.RowsAffected() == 0 {
+		return storage.ErrNotFound
+	}
+
+	return nil
+}
+
+func (s Store) ListWebhookDeliveries(
+	ctx context.Context,
+	status string,
+	afterID int64,
+	limit int,
+) (*storage.WebhookDeliveryPage, error) {
+	if err := s.connected(); err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]*model.WebhookDelivery, 0)
+
+	ctx, cancel := s.withQueryTimeout(ctx)
+	defer cancel()
+
+	// fetch one extra row to find out whether there is another page
+	err := s.db.ModelContext(ctx, &deliveries).
+		Where("status = ?", status).
+		Where("id > ?", afterID).
+		Order("id ASC").
+		Limit(limit + 1).
+		Select()
+	if err != nil {
+		return nil, err
+	}
+
+	return storage.NewWebhookDeliveryPage(deliveries, limit), nil
 }
@@ -1,18 +1,75 @@
 package storage
 
 import (
+	"context"
+	"encoding/hex"
 	"errors"
+	"strings"
+	"time"
+
+	"github.com/onflow/flow-go-sdk"
 
 	"github.com/onflow/flow-account-api/model"
 )
 
 var (
-	ErrNotFound = errors.New("not found")
-	ErrExists   = errors.New("already exists")
+	ErrNotFound       = errors.New("not found")
+	ErrExists         = errors.New("already exists")
+	ErrInvalidAddress = errors.New("invalid address")
 )
 
+// Store is the registry of accounts created by the service.
+//
+// Every method takes a context so that cancellations and deadlines
+// of the originating request reach the underlying storage.
 type Store interface {
-	InsertAccount(account *model.Account) error
-	GetAccountByPublicKey(publicKey string, account *model.Account) error
-	GetAccountCount() (int, error)
+	// InsertAccount stores an account. If event is not nil, it is appended to the audit log in the same
+	// transaction and assigned its ID, so that an account is never stored without the event recording it.
+	InsertAccount(ctx context.Context, account *model.Account, event *model.AuditEvent) error
+	// GetAccountByPublicKey returns the first of the accounts associated with a public key.
+	GetAccountByPublicKey(ctx context.Context, publicKey string, account *model.Account) error
+	// GetAccountsByPublicKey returns all accounts associated with a public key, ordered by address.
+	GetAccountsByPublicKey(ctx context.Context, publicKey string) ([]*model.Account, error)
+	// GetAccountsByPublicKeys returns the accounts associated with each of the given public keys.
+	// Public keys without an account are omitted from the result.
+	GetAccountsByPublicKeys(ctx context.Context, publicKeys []string) (map[string][]*model.Account, error)
+	GetAccountByAddress(ctx context.Context, address string, account *model.Account) error
+	GetAccountCount(ctx context.Context) (int, error)
+	// ListAccounts returns up to limit accounts matching the filter that come after the cursor.
+	ListAccounts(ctx context.Context, filter AccountFilter, cursor Cursor, limit int) (*AccountPage, error)
+	// Ping checks that the store is available.
+	Ping(ctx context.Context) error
+	// InsertAuditEvent appends an event to the audit log, assigning its ID.
+	InsertAuditEvent(ctx context.Context, event *model.AuditEvent) error
+	// ListAuditEvents returns up to limit events matching the filter that were recorded after the event with the given ID.
+	ListAuditEvents(ctx context.Context, filter AuditEventFilter, afterID int64, limit int) (*AuditEventPage, error)
+	// InsertWebhookDeliveries queues deliveries in the webhook outbox, assigning their IDs.
+	InsertWebhookDeliveries(ctx context.Context, deliveries []*model.WebhookDelivery) error
+	// ClaimWebhookDeliveries returns up to limit pending deliveries that are due at now,
+	// postponing their next attempt until now plus lease so that no other dispatcher claims them meanwhile.
+	ClaimWebhookDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*model.WebhookDelivery, error)
+	// UpdateWebhookDelivery records the outcome of an attempt to send a delivery.
+	UpdateWebhookDelivery(ctx context.Context, delivery *model.WebhookDelivery) error
+	// ListWebhookDeliveries returns up to limit deliveries with the given status that were queued after the delivery with the given ID.
+	ListWebhookDeliveries(ctx context.Context, status string, afterID int64, limit int) (*WebhookDeliveryPage, error)
+}
+
+// NormalizeAddress converts an account address to the form in which stores
+// index it: lowercase hex without a 0x prefix, left-padded with zeros to the
+// full address length.
+func NormalizeAddress(address string) (string, error) {
+	address = strings.ToLower(strings.TrimSpace(address))
+	address = strings.TrimPrefix(address, "0x")
+
+	if address == "" || len(address) > 2*flow.AddressLength {
+		return "", ErrInvalidAddress
+	}
+
+	address = strings.Repeat("0", 2*flow.AddressLength-len(address)) + address
+
+	if _, err := hex.DecodeString(address); err != nil {
+		return "", ErrInvalidAddress
+	}
+
+	return address, nil
 }
@@ -0,0 +1,558 @@
+// Package storetest checks that implementations of storage.Store behave alike.
+//
+// Each backend runs the suite from its own tests:
+//
+//	func TestStore(t *testing.T) {
+//		storetest.Run(t, func(t *testing.T) storage.Store {
+//			return newEmptyStore(t)
+//		})
+//	}
+package storetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/onflow/flow-account-api/model"
+	"github.com/onflow/flow-account-api/storage"
+)
+
+// Run runs the suite against the stores returned by newStore, which must be empty.
+// newStore is called once for each test.
+func Run(t *testing.T, newStore func(t *testing.T) storage.Store) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, store storage.Store)
+	}{
+		{"InsertAndGetByAddress", testInsertAndGetByAddress},
+		{"DuplicateAddress", testDuplicateAddress},
+		{"DuplicatePublicKeyInAccount", testDuplicatePublicKeyInAccount},
+		{"InsertAccountWithAuditEvent", testInsertAccountWithAuditEvent},
+		{"SharedPublicKey", testSharedPublicKey},
+		{"GetAccountsByPublicKeys", testGetAccountsByPublicKeys},
+		{"GetAccountCount", testGetAccountCount},
+		{"ListAccountsPagination", testListAccountsPagination},
+		{"ListAccountsFilter", testListAccountsFilter},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			test.test(t, newStore(t))
+		})
+	}
+}
+
+// createdAt is the creation time of the accounts inserted by the tests, truncated to the
+// precision that every backend stores.
+var createdAt = time.Date(2021, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+// publicKey returns a distinct public key for each n.
+func publicKey(n int) string {
+	return fmt.Sprintf("%0128x", n)
+}
+
+// newAccount returns an account at the given address, controlled by the given public keys.
+func newAccount(address string, createdAt time.Time, publicKeys ...string) *model.Account {
+	account := &model.Account{
+		Address:               address,
+		CreationTransactionID: "tx-" + address,
+		CreatedAt:             createdAt,
+		UpdatedAt:             createdAt,
+	}
+
+	for _, key := range publicKeys {
+		account.PublicKeys = append(account.PublicKeys, &model.AccountPublicKey{
+			PublicKey: key,
+			SigAlgo:   "ECDSA_P256",
+			HashAlgo:  "SHA3_256",
+		})
+	}
+
+	return account
+}
+
+func insertAccount(t *testing.T, store storage.Store, account *model.Account) {
+	t.Helper()
+
+	err := store.InsertAccount(context.Background(), account, nil)
+	if err != nil {
+		t.Fatalf("failed to insert account %s: %v", account.Address, err)
+	}
+}
+
+// addresses returns the addresses of accounts, in order.
+func addresses(accounts []*model.Account) []string {
+	result := make([]string, len(accounts))
+	for i, account := range accounts {
+		result[i] = account.Address
+	}
+
+	return result
+}
+
+// publicKeys returns the sorted public keys of an account.
+func publicKeys(account *model.Account) []string {
+	result := make([]string, len(account.PublicKeys))
+	for i, publicKey := range account.PublicKeys {
+		result[i] = publicKey.PublicKey
+	}
+
+	sort.Strings(result)
+
+	return result
+}
+
+func assertAddresses(t *testing.T, accounts []*model.Account, want ...string) {
+	t.Helper()
+
+	got := addresses(accounts)
+	if len(got) == 0 && len(want) == 0 {
+		return
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got accounts %v, want %v", got, want)
+	}
+}
+
+func assertCount(t *testing.T, store storage.Store, want int) {
+	t.Helper()
+
+	count, err := store.GetAccountCount(context.Background())
+	if err != nil {
+		t.Fatalf("failed to count accounts: %v", err)
+	}
+
+	if count != want {
+		t.Errorf("got %d accounts, want %d", count, want)
+	}
+}
+
+func testInsertAndGetByAddress(t *testing.T, store storage.Store) {
+	ctx := context.Background()
+
+	account := newAccount("0x00000000000000AB", createdAt, publicKey(1), publicKey(2))
+	account.Network = "emulator"
+	account.ClientID = "client"
+
+	insertAccount(t, store, account)
+
+	if account.Address != "00000000000000ab" {
+		t.Errorf("inserted account has address %q, want it normalized to %q", account.Address, "00000000000000ab")
+	}
+
+	// addresses are looked up in any of the forms NormalizeAddress accepts
+	for _, address := range []string{"00000000000000ab", "0xab", "AB"} {
+		var got model.Account
+
+		err := store.GetAccountByAddress(ctx, address, &got)
+		if err != nil {
+			t.Fatalf("failed to get account by address %q: %v", address, err)
+		}
+
+		if got.Address != "00000000000000ab" ||
+			got.CreationTransactionID != account.CreationTransactionID ||
+			got.Network != account.Network ||
+			got.ClientID != account.ClientID ||
+			!got.CreatedAt.Equal(account.CreatedAt) {
+			t.Errorf("got account %+v by address %q, want %+v", got, address, *account)
+		}
+
+		if keys := publicKeys(&got); !reflect.DeepEqual(keys, []string{publicKey(1), publicKey(2)}) {
+			t.Errorf("got public keys %v by address %q, want the two inserted keys", keys, address)
+		}
+	}
+
+	var got model.Account
+
+	err := store.GetAccountByAddress(ctx, "0xcd", &got)
+	if !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("got error %v for a missing address, want %v", err, storage.ErrNotFound)
+	}
+
+	err = store.GetAccountByAddress(ctx, "not an address", &got)
+	if !errors.Is(err, storage.ErrInvalidAddress) {
+		t.Errorf("got error %v for an invalid address, want %v", err, storage.ErrInvalidAddress)
+	}
+
+	err = store.InsertAccount(ctx, newAccount("not an address", createdAt, publicKey(3)), nil)
+	if !errors.Is(err, storage.ErrInvalidAddress) {
+		t.Errorf("got error %v inserting an invalid address, want %v", err, storage.ErrInvalidAddress)
+	}
+
+	assertCount(t, store, 1)
+}
+
+func testDuplicateAddress(t *testing.T, store storage.Store) {
+	ctx := context.Background()
+
+	insertAccount(t, store, newAccount("01", createdAt, publicKey(1)))
+
+	// the conflicting account also holds a new key, which must not be indexed
+	err := store.InsertAccount(ctx, newAccount("0x01", createdAt.Add(time.Minute), publicKey(1), publicKey(2)), nil)
+	if !errors.Is(err, storage.ErrExists) {
+		t.Fatalf("got error %v inserting a duplicate address, want %v", err, storage.ErrExists)
+	}
+
+	assertCount(t, store, 1)
+
+	_, err = store.GetAccountsByPublicKey(ctx, publicKey(2))
+	if !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("got error %v for the key of the rejected account, want %v", err, storage.ErrNotFound)
+	}
+
+	var got model.Account
+
+	err = store.GetAccountByAddress(ctx, "01", &got)
+	if err != nil {
+		t.Fatalf("failed to get account by address: %v", err)
+	}
+
+	if !got.CreatedAt.Equal(createdAt) || !reflect.DeepEqual(publicKeys(&got), []string{publicKey(1)}) {
+		t.Errorf("got account %+v, want the account inserted first", got)
+	}
+}
+
+func testDuplicatePublicKeyInAccount(t *testing.T, store storage.Store) {
+	ctx := context.Background()
+
+	// the account conflicts with itself only after its first key has been stored
+	err := store.InsertAccount(ctx, newAccount("02", createdAt, publicKey(1), publicKey(2), publicKey(1)), nil)
+	if !errors.Is(err, storage.ErrExists) {
+		t.Fatalf("got error %v inserting an account with a repeated key, want %v", err, storage.ErrExists)
+	}
+
+	assertCount(t, store, 0)
+
+	var got model.Account
+
+	err = store.GetAccountByAddress(ctx, "02", &got)
+	if !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("got error %v for the rejected account, want %v", err, storage.ErrNotFound)
+	}
+
+	for _, key := range []string{publicKey(1), publicKey(2)} {
+		_, err = store.GetAccountsByPublicKey(ctx, key)
+		if !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("got error %v for a key of the rejected account, want %v", err, storage.ErrNotFound)
+		}
+	}
+
+	// the address is free to be used once the insert has been rejected
+	insertAccount(t, store, newAccount("02", createdAt, publicKey(1)))
+	assertCount(t, store, 1)
+}
+
+func testInsertAccountWithAuditEvent(t *testing.T, store storage.Store) {
+	ctx := context.Background()
+
+	newEvent := func(address string) *model.AuditEvent {
+		return &model.AuditEvent{
+			Type:       model.AuditEventAccountCreated,
+			Actor:      model.AuditActorClient,
+			Address:    address,
+			PublicKeys: []string{publicKey(1)},
+			CreatedAt:  createdAt,
+		}
+	}
+
+	event := newEvent("0000000000000001")
+
+	err := store.InsertAccount(ctx, newAccount("01", createdAt, publicKey(1)), event)
+	if err != nil {
+		t.Fatalf("failed to insert account with audit event: %v", err)
+	}
+
+	if event.ID == 0 {
+		t.Errorf("audit event stored with the account was not assigned an ID")
+	}
+
+	// neither a conflicting address nor a conflicting key may leave an event behind
+	rejected := []*model.Account{
+		newAccount("01", createdAt, publicKey(2)),
+		newAccount("02", createdAt, publicKey(1), publicKey(1)),
+	}
+
+	for _, account := range rejected {
+		err = store.InsertAccount(ctx, account, newEvent(account.Address))
+		if !errors.Is(err, storage.ErrExists) {
+			t.Fatalf("got error %v inserting a conflicting account, want %v", err, storage.ErrExists)
+		}
+	}
+
+	page, err := store.ListAuditEvents(ctx, storage.AuditEventFilter{}, 0, 10)
+	if err != nil {
+		t.Fatalf("failed to list audit events: %v", err)
+	}
+
+	if len(page.Events) != 1 {
+		t.Fatalf("got %d audit events, want only the event of the stored account", len(page.Events))
+	}
+
+	got := page.Events[0]
+
+	if got.ID != event.ID || got.Address != event.Address || got.Type != event.Type ||
+		!reflect.DeepEqual(got.PublicKeys, event.PublicKeys) {
+		t.Errorf("got audit event %+v, want %+v", *got, *event)
+	}
+}
+
+func testSharedPublicKey(t *testing.T, store storage.Store) {
+	ctx := context.Background()
+
+	// inserted out of address order, to check that lookups order by address
+	insertAccount(t, store, newAccount("03", createdAt, publicKey(1)))
+	insertAccount(t, store, newAccount("01", createdAt, publicKey(1), publicKey(2)))
+	insertAccount(t, store, newAccount("02", createdAt, publicKey(2)))
+
+	accounts, err := store.GetAccountsByPublicKey(ctx, publicKey(1))
+	if err != nil {
+		t.Fatalf("failed to get accounts by public key: %v", err)
+	}
+
+	assertAddresses(t, accounts, "0000000000000001", "0000000000000003")
+
+	var first model.Account
+
+	err = store.GetAccountByPublicKey(ctx, publicKey(1), &first)
+	if err != nil {
+		t.Fatalf("failed to get account by public key: %v", err)
+	}
+
+	if first.Address != "0000000000000001" {
+		t.Errorf("got account %s by public key, want the lowest address %s", first.Address, "0000000000000001")
+	}
+
+	// accounts found by one key are returned with all of their keys
+	if keys := publicKeys(&first); !reflect.DeepEqual(keys, []string{publicKey(1), publicKey(2)}) {
+		t.Errorf("got public keys %v, want both keys of the account", keys)
+	}
+
+	_, err = store.GetAccountsByPublicKey(ctx, publicKey(3))
+	if !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("got error %v for an unknown key, want %v", err, storage.ErrNotFound)
+	}
+
+	err = store.GetAccountByPublicKey(ctx, publicKey(3), &first)
+	if !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("got error %v for an unknown key, want %v", err, storage.ErrNotFound)
+	}
+}
+
+func testGetAccountsByPublicKeys(t *testing.T, store storage.Store) {
+	ctx := context.Background()
+
+	insertAccount(t, store, newAccount("02", createdAt, publicKey(1), publicKey(2)))
+	insertAccount(t, store, newAccount("01", createdAt, publicKey(1)))
+
+	accounts, err := store.GetAccountsByPublicKeys(ctx, []string{publicKey(1), publicKey(2), publicKey(3)})
+	if err != nil {
+		t.Fatalf("failed to get accounts by public keys: %v", err)
+	}
+
+	if len(accounts) != 2 {
+		t.Errorf("got accounts for %d keys, want 2, omitting the unknown key", len(accounts))
+	}
+
+	assertAddresses(t, accounts[publicKey(1)], "0000000000000001", "0000000000000002")
+	assertAddresses(t, accounts[publicKey(2)], "0000000000000002")
+
+	if _, ok := accounts[publicKey(3)]; ok {
+		t.Errorf("got accounts for an unknown key, want it omitted")
+	}
+
+	accounts, err = store.GetAccountsByPublicKeys(ctx, nil)
+	if err != nil {
+		t.Fatalf("failed to get accounts by no public keys: %v", err)
+	}
+
+	if len(accounts) != 0 {
+		t.Errorf("got accounts for %d keys when none were requested", len(accounts))
+	}
+}
+
+func testGetAccountCount(t *testing.T, store storage.Store) {
+	assertCount(t, store, 0)
+
+	for i := 1; i <= 3; i++ {
+		insertAccount(t, store, newAccount(fmt.Sprintf("%x", i), createdAt, publicKey(i)))
+		assertCount(t, store, i)
+	}
+
+	// a rejected insert is not counted
+	err := store.InsertAccount(context.Background(), newAccount("1", createdAt, publicKey(4)), nil)
+	if !errors.Is(err, storage.ErrExists) {
+		t.Fatalf("got error %v inserting a duplicate address, want %v", err, storage.ErrExists)
+	}
+
+	assertCount(t, store, 3)
+}
+
+func testListAccountsPagination(t *testing.T, store storage.Store) {
+	ctx := context.Background()
+
+	// accounts created at the same time are ordered by address
+	insertAccount(t, store, newAccount("05", createdAt, publicKey(5)))
+	insertAccount(t, store, newAccount("04", createdAt.Add(-time.Hour), publicKey(4)))
+	insertAccount(t, store, newAccount("02", createdAt, publicKey(2)))
+	insertAccount(t, store, newAccount("01", createdAt.Add(time.Hour), publicKey(1)))
+	insertAccount(t, store, newAccount("03", createdAt, publicKey(3)))
+
+	want := []string{
+		"0000000000000004",
+		"0000000000000002",
+		"0000000000000003",
+		"0000000000000005",
+		"0000000000000001",
+	}
+
+	page, err := store.ListAccounts(ctx, storage.AccountFilter{}, storage.Cursor{}, len(want))
+	if err != nil {
+		t.Fatalf("failed to list accounts: %v", err)
+	}
+
+	assertAddresses(t, page.Accounts, want...)
+
+	if page.NextCursor != "" {
+		t.Errorf("got next cursor %q for a page holding every account, want none", page.NextCursor)
+	}
+
+	for _, limit := range []int{1, 2, 3} {
+		t.Run(fmt.Sprintf("Limit%d", limit), func(t *testing.T) {
+			var (
+				got    []string
+				cursor storage.Cursor
+			)
+
+			for pages := 0; ; pages++ {
+				if pages > len(want) {
+					t.Fatalf("listing did not end after %d pages", pages)
+				}
+
+				page, err := store.ListAccounts(ctx, storage.AccountFilter{}, cursor, limit)
+				if err != nil {
+					t.Fatalf("failed to list accounts: %v", err)
+				}
+
+				if len(page.Accounts) > limit {
+					t.Fatalf("got %d accounts in a page limited to %d", len(page.Accounts), limit)
+				}
+
+				got = append(got, addresses(page.Accounts)...)
+
+				if page.NextCursor == "" {
+					break
+				}
+
+				// cursors are passed through clients, so they are resumed from their encoding
+				cursor, err = storage.DecodeCursor(page.NextCursor)
+				if err != nil {
+					t.Fatalf("failed to decode cursor %q: %v", page.NextCursor, err)
+				}
+			}
+
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got accounts %v across pages, want %v", got, want)
+			}
+		})
+	}
+
+	// a cursor after the last account ends the listing
+	last := &model.Account{Address: want[len(want)-1], CreatedAt: createdAt.Add(time.Hour)}
+
+	page, err = store.ListAccounts(ctx, storage.AccountFilter{}, storage.CursorAfter(last), len(want))
+	if err != nil {
+		t.Fatalf("failed to list accounts: %v", err)
+	}
+
+	assertAddresses(t, page.Accounts)
+
+	if page.NextCursor != "" {
+		t.Errorf("got next cursor %q after the last account, want none", page.NextCursor)
+	}
+}
+
+func testListAccountsFilter(t *testing.T, store storage.Store) {
+	ctx := context.Background()
+
+	first := newAccount("01", createdAt, publicKey(1))
+	first.ClientID = "wallet"
+
+	second := newAccount("02", createdAt.Add(time.Hour), publicKey(2))
+	second.PublicKeys[0].SigAlgo = "ECDSA_secp256k1"
+	second.ClientID = "wallet"
+
+	third := newAccount("03", createdAt.Add(2*time.Hour), publicKey(3))
+
+	for _, account := range []*model.Account{first, second, third} {
+		insertAccount(t, store, account)
+	}
+
+	tests := []struct {
+		name   string
+		filter storage.AccountFilter
+		want   []string
+	}{
+		{
+			name:   "CreatedAfter",
+			filter: storage.AccountFilter{CreatedAfter: createdAt.Add(time.Hour)},
+			want:   []string{"0000000000000002", "0000000000000003"},
+		},
+		{
+			name:   "CreatedBefore",
+			filter: storage.AccountFilter{CreatedBefore: createdAt.Add(time.Hour)},
+			want:   []string{"0000000000000001"},
+		},
+		{
+			name:   "SigAlgo",
+			filter: storage.AccountFilter{SigAlgo: "ECDSA_secp256k1"},
+			want:   []string{"0000000000000002"},
+		},
+		{
+			name:   "ClientID",
+			filter: storage.AccountFilter{ClientID: "wallet"},
+			want:   []string{"0000000000000001", "0000000000000002"},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			// a limit of one checks that filtered listings paginate as well
+			var (
+				got    []string
+				cursor storage.Cursor
+			)
+
+			for pages := 0; pages <= len(test.want); pages++ {
+				page, err := store.ListAccounts(ctx, test.filter, cursor, 1)
+				if err != nil {
+					t.Fatalf("failed to list accounts: %v", err)
+				}
+
+				got = append(got, addresses(page.Accounts)...)
+
+				if page.NextCursor == "" {
+					break
+				}
+
+				cursor, err = storage.DecodeCursor(page.NextCursor)
+				if err != nil {
+					t.Fatalf("failed to decode cursor %q: %v", page.NextCursor, err)
+				}
+			}
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got accounts %v, want %v", got, test.want)
+			}
+		})
+	}
+}
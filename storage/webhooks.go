@@ -0,0 +1,26 @@
+package storage
+
+import (
+	"github.com/onflow/flow-account-api/model"
+)
+
+// WebhookDeliveryPage is a page of webhook deliveries in the order they were queued.
+type WebhookDeliveryPage struct {
+	Deliveries []*model.WebhookDelivery `json:"deliveries"`
+	// NextCursor resumes the listing after the last delivery in this page.
+	// It is empty if there are no more deliveries.
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// NewWebhookDeliveryPage returns a page of at most limit deliveries from deliveries,
+// which may hold one more delivery than the limit to signal that there is another page.
+func NewWebhookDeliveryPage(deliveries []*model.WebhookDelivery, limit int) *WebhookDeliveryPage {
+	page := &WebhookDeliveryPage{Deliveries: deliveries}
+
+	if len(deliveries) > limit {
+		page.Deliveries = deliveries[:limit]
+		page.NextCursor = EncodeIDCursor(page.Deliveries[limit-1].ID)
+	}
+
+	return page
+}
@@ -2,27 +2,34 @@ package wallet
 
 import (
 	"context"
-	"encoding/hex"
 	"fmt"
 	"time"
 
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/label"
 	"google.golang.org/grpc"
 
+	"github.com/onflow/flow-account-api/model"
+	"github.com/onflow/flow-account-api/pkg/logging"
+	"github.com/onflow/flow-account-api/pkg/tracing"
 	"github.com/onflow/flow-go-sdk"
-	"github.com/onflow/flow-go-sdk/templates"
 	"github.com/onflow/flow-go-sdk/client"
 	"github.com/onflow/flow-go-sdk/crypto"
-	"github.com/onflow/flow-account-api/model"
+	"github.com/onflow/flow-go-sdk/templates"
 )
 
 const gasLimit = 100
 
 type Accounts struct {
-	flowClient                  *client.Client
-	creatorAddress              flow.Address
-	creatorKeyIndex             int
-	creatorSigner               crypto.Signer
-	accountLimit                int
+	flowClient      *client.Client
+	creatorAddress  flow.Address
+	creatorKeyIndex int
+	creatorSigner   crypto.Signer
+	accountLimit    int
+	network         string
+	sealTimeout     time.Duration
+	metrics         *AccountsCollector
+	logger          zerolog.Logger
 }
 
 func NewAccounts(
@@ -31,6 +38,10 @@ func NewAccounts(
 	creatorKeyIndex int,
 	creatorSigner crypto.Signer,
 	accountLimit int,
+	network string,
+	sealTimeout time.Duration,
+	metrics *AccountsCollector,
+	logger zerolog.Logger,
 ) (*Accounts, error) {
 	flowClient, err := client.New(accessAddress, grpc.WithInsecure())
 	if err != nil {
@@ -38,51 +49,108 @@ func NewAccounts(
 	}
 
 	return &Accounts{
-		flowClient:                  flowClient,
-		creatorAddress:              creatorAddress,
-		creatorKeyIndex:             creatorKeyIndex,
-		creatorSigner:               creatorSigner,
-		accountLimit:                accountLimit,
+		flowClient:      flowClient,
+		creatorAddress:  creatorAddress,
+		creatorKeyIndex: creatorKeyIndex,
+		creatorSigner:   creatorSigner,
+		accountLimit:    accountLimit,
+		network:         network,
+		sealTimeout:     sealTimeout,
+		metrics:         metrics,
+		logger:          logger,
 	}, nil
 }
 
-func (a *Accounts) Create(newAccountKey *flow.AccountKey) (*model.Account, error) {
-	ctx := context.Background()
+// Create creates a new account controlled by the given key and waits for it to be sealed.
+func (a *Accounts) Create(
+	ctx context.Context,
+	newAccountKey *flow.AccountKey,
+	client model.ClientMetadata,
+) (account *model.Account, err error) {
+	ctx, span := tracing.StartSpan(ctx, "Accounts.Create")
+	defer func() { tracing.EndSpan(ctx, span, err) }()
 
 	accountCreatorKey, err := a.getAccountKey(ctx, a.creatorAddress, a.creatorKeyIndex)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get account creator key: %w", err)
+		return nil, &creationError{
+			class: creationErrorAccessAPI,
+			err:   fmt.Errorf("failed to get account creator key: %w", err),
+		}
 	}
 
-	latestBlock, err := a.flowClient.GetLatestBlockHeader(ctx, true)
+	var latestBlock *flow.BlockHeader
+
+	err = a.callAccessAPI(ctx, "GetLatestBlockHeader", func(ctx context.Context) (err error) {
+		latestBlock, err = a.flowClient.GetLatestBlockHeader(ctx, true)
+		return err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get latest block header: %w", err)
+		return nil, &creationError{
+			class: creationErrorAccessAPI,
+			err:   fmt.Errorf("failed to get latest block header: %w", err),
+		}
 	}
 
 	tx := a.createAccountTransaction(
-		a.creatorAddress, 
-		accountCreatorKey, 
-		newAccountKey, 
-		latestBlock.ID, 
+		a.creatorAddress,
+		accountCreatorKey,
+		newAccountKey,
+		latestBlock.ID,
 	)
 
 	err = tx.SignEnvelope(a.creatorAddress, accountCreatorKey.Index, a.creatorSigner)
 	if err != nil {
-		return nil, fmt.Errorf("failed to sign transaction: %w", err)
+		return nil, &creationError{
+			class: creationErrorSigning,
+			err:   fmt.Errorf("failed to sign transaction: %w", err),
+		}
 	}
 
-	err = a.flowClient.SendTransaction(ctx, *tx)
+	ctx = logging.With(ctx, a.logger, func(c zerolog.Context) zerolog.Context {
+		return c.Str("transactionId", tx.ID().Hex())
+	})
+
+	logger := logging.FromContext(ctx, a.logger)
+
+	logger.Debug().
+		Int("creatorKeyIndex", accountCreatorKey.Index).
+		Uint64("referenceBlockHeight", latestBlock.Height).
+		Msg("sending account creation transaction")
+
+	err = a.callAccessAPI(ctx, "SendTransaction", func(ctx context.Context) error {
+		return a.flowClient.SendTransaction(ctx, *tx)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to send transaction: %w", err)
+		return nil, &creationError{
+			class:         creationErrorAccessAPI,
+			err:           fmt.Errorf("failed to send transaction: %w", err),
+			transactionID: tx.ID().Hex(),
+		}
 	}
 
-	result, err := waitForSeal(ctx, a.flowClient, tx.ID())
+	submitted := time.Now()
+
+	result, err := a.waitForSeal(ctx, tx.ID())
 	if err != nil {
-		return nil, fmt.Errorf("failed to get transaction result: %w", err)
+		return nil, &creationError{
+			class:         creationErrorAccessAPI,
+			err:           fmt.Errorf("failed to get transaction result: %w", err),
+			transactionID: tx.ID().Hex(),
+		}
 	}
 
+	a.metrics.transactionSealed(time.Since(submitted))
+
+	logger.Debug().
+		Dur("sealDuration", time.Since(submitted)).
+		Msg("account creation transaction sealed")
+
 	if result.Error != nil {
-		return nil, fmt.Errorf("failed to execute transaction (id=%s): %w", tx.ID(), result.Error)
+		return nil, &creationError{
+			class:         creationErrorTransactionFailed,
+			err:           fmt.Errorf("failed to execute transaction (id=%s): %w", tx.ID(), result.Error),
+			transactionID: tx.ID().Hex(),
+		}
 	}
 
 	var address flow.Address
@@ -94,7 +162,7 @@ func (a *Accounts) Create(newAccountKey *flow.AccountKey) (*model.Account, error
 		}
 	}
 
-	publicKey := hex.EncodeToString(newAccountKey.PublicKey.Encode())
+	publicKey := encodePublicKey(newAccountKey.PublicKey)
 
 	return &model.Account{
 		Address:               address.Hex(),
@@ -107,6 +175,13 @@ func (a *Accounts) Create(newAccountKey *flow.AccountKey) (*model.Account, error
 				HashAlgo:  newAccountKey.HashAlgo.String(),
 			},
 		},
+		CreatedAt:           time.Now().UTC(),
+		CreationBlockHeight: latestBlock.Height,
+		Network:             a.network,
+		CreatorKeyIndex:     accountCreatorKey.Index,
+		ClientID:            client.ID,
+		ClientIP:            client.IP,
+		ClientUserAgent:     client.UserAgent,
 	}, nil
 }
 
@@ -115,16 +190,21 @@ func (a *Accounts) GetLimit() int {
 }
 
 func (a *Accounts) getAccountKey(
-	ctx context.Context, 
-	address flow.Address, 
+	ctx context.Context,
+	address flow.Address,
 	index int,
 ) (*flow.AccountKey, error) {
-	account, err := a.flowClient.GetAccountAtLatestBlock(ctx, address)
+	var account *flow.Account
+
+	err := a.callAccessAPI(ctx, "GetAccountAtLatestBlock", func(ctx context.Context) (err error) {
+		account, err = a.flowClient.GetAccountAtLatestBlock(ctx, address)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	if len(account.Keys) < index {
+	if len(account.Keys) <= index {
 		return nil, fmt.Errorf("account with address %s does not contain key at index %d", address, index)
 	}
 
@@ -150,15 +230,40 @@ func (a *Accounts) createAccountTransaction(
 		SetPayer(creatorAddress)
 }
 
-func waitForSeal(ctx context.Context, flowClient *client.Client, id flow.Identifier) (*flow.TransactionResult, error) {
-	result, err := flowClient.GetTransactionResult(ctx, id)
+// sealPollInterval is how often the result of a transaction is checked while waiting for it to be sealed.
+const sealPollInterval = time.Second
+
+// waitForSeal polls the result of a transaction until it is sealed, for up to the seal timeout.
+//
+// Account creations are detached from the requests that started them, so the timeout is what
+// bounds how long a creation can hold up shutdown.
+func (a *Accounts) waitForSeal(ctx context.Context, id flow.Identifier) (result *flow.TransactionResult, err error) {
+	ctx, span := tracing.StartSpan(ctx, "Accounts.waitForSeal", label.String("transactionId", id.Hex()))
+	defer func() { tracing.EndSpan(ctx, span, err) }()
+
+	if a.sealTimeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, a.sealTimeout)
+		defer cancel()
+	}
+
+	result, err = a.getTransactionResult(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
+	ticker := time.NewTicker(sealPollInterval)
+	defer ticker.Stop()
+
 	for result.Status != flow.TransactionStatusSealed {
-		time.Sleep(time.Second)
-		result, err = flowClient.GetTransactionResult(ctx, id)
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("transaction was not sealed within %s: %w", a.sealTimeout, ctx.Err())
+		case <-ticker.C:
+		}
+
+		result, err = a.getTransactionResult(ctx, id)
 		if err != nil {
 			return nil, err
 		}
@@ -167,3 +272,57 @@ func waitForSeal(ctx context.Context, flowClient *client.Client, id flow.Identif
 	return result, nil
 }
 
+func (a *Accounts) getTransactionResult(ctx context.Context, id flow.Identifier) (result *flow.TransactionResult, err error) {
+	err = a.callAccessAPI(ctx, "GetTransactionResult", func(ctx context.Context) (err error) {
+		result, err = a.flowClient.GetTransactionResult(ctx, id)
+		return err
+	})
+
+	return result, err
+}
+
+// callAccessAPI makes a call to the access API, recording its latency and a span for it.
+func (a *Accounts) callAccessAPI(ctx context.Context, method string, call func(ctx context.Context) error) error {
+	ctx, span := tracing.StartSpan(ctx, "AccessAPI."+method)
+
+	start := time.Now()
+	err := call(ctx)
+	a.metrics.accessAPIRequest(method, start, err)
+
+	tracing.EndSpan(ctx, span, err)
+
+	return err
+}
+
+// Ping checks that the access API is reachable.
+func (a *Accounts) Ping(ctx context.Context) error {
+	return a.callAccessAPI(ctx, "Ping", func(ctx context.Context) error {
+		return a.flowClient.Ping(ctx)
+	})
+}
+
+// CheckCreatorKey checks that the creator key can still sign account creation transactions:
+// it must exist on chain, not be revoked, carry full weight and match the configured private key.
+func (a *Accounts) CheckCreatorKey(ctx context.Context) error {
+	key, err := a.getAccountKey(ctx, a.creatorAddress, a.creatorKeyIndex)
+	if err != nil {
+		return fmt.Errorf("failed to get account creator key: %w", err)
+	}
+
+	if key.Revoked {
+		return fmt.Errorf("account creator key %d is revoked", key.Index)
+	}
+
+	if key.Weight < flow.AccountKeyWeightThreshold {
+		return fmt.Errorf("account creator key %d has insufficient weight %d", key.Index, key.Weight)
+	}
+
+	// only in-memory signers expose their key for comparison
+	if signer, ok := a.creatorSigner.(crypto.InMemorySigner); ok {
+		if !signer.PrivateKey.PublicKey().Equals(key.PublicKey) {
+			return fmt.Errorf("account creator key %d does not match the configured private key", key.Index)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,177 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/onflow/flow-account-api/model"
+	"github.com/onflow/flow-account-api/storage"
+)
+
+// newCreationEvent returns the audit event recording an attempt to create an account.
+//
+// The account is nil if the attempt failed before the account was created on chain, and err is nil
+// if the account was created and is about to be stored.
+func newCreationEvent(
+	ctx context.Context,
+	client model.ClientMetadata,
+	publicKey string,
+	account *model.Account,
+	err error,
+) *model.AuditEvent {
+	event := &model.AuditEvent{
+		Type:            model.AuditEventAccountCreated,
+		Actor:           model.AuditActorClient,
+		RequestID:       requestIDFromContext(ctx),
+		ClaimedClientID: client.ID,
+		ClientIP:        client.IP,
+		ClientUserAgent: client.UserAgent,
+	}
+
+	// keys are recorded in their canonical encoding, so that the log can be searched by key
+	normalizedPublicKey, keyErr := NormalizePublicKey(publicKey)
+	if keyErr == nil {
+		event.PublicKeys = []string{normalizedPublicKey}
+	} else {
+		event.RejectedPublicKey = publicKey
+	}
+
+	if client.Admin {
+		event.Actor = model.AuditActorAdmin
+	}
+
+	if account != nil {
+		event.Address = account.Address
+		event.TransactionID = account.CreationTransactionID
+	}
+
+	if err != nil {
+		event.Type = model.AuditEventAccountCreationFailed
+		event.Error = err.Error()
+
+		var creationErr *creationError
+		if errors.As(err, &creationErr) {
+			event.TransactionID = creationErr.transactionID
+		}
+	}
+
+	return event
+}
+
+// recordRejectedCreation records a request to create an account that was rejected
+// before anything was sent to chain, such as one with an invalid key or over the account limit.
+//
+// Rejections are only recorded in the audit log: anyone who can reach the API can cause them,
+// so they are not sent to webhook subscribers.
+func (s *Service) recordRejectedCreation(
+	ctx context.Context,
+	client model.ClientMetadata,
+	publicKey string,
+	err error,
+) {
+	s.recordAuditEvent(ctx, newCreationEvent(ctx, client, publicKey, nil, err))
+}
+
+// recordFailedCreation records a failed attempt to create an account for a valid request
+// in the audit log and queues webhook callbacks for it.
+//
+// Successful creations are recorded by the same transaction that stores the account instead.
+func (s *Service) recordFailedCreation(
+	ctx context.Context,
+	client model.ClientMetadata,
+	publicKey string,
+	account *model.Account,
+	err error,
+) {
+	event := newCreationEvent(ctx, client, publicKey, account, err)
+
+	s.recordAuditEvent(ctx, event)
+	s.queueWebhooks(ctx, event)
+}
+
+// auditRetryDelays are the waits between attempts to record an audit event.
+var auditRetryDelays = []time.Duration{100 * time.Millisecond, 500 * time.Millisecond, 2 * time.Second}
+
+// recordAuditEvent appends an event to the audit log, retrying a few times if the store fails.
+//
+// The failure being recorded has already been reported to the client, so an event that still
+// cannot be recorded is logged in full at error level for operators to restore by hand.
+func (s *Service) recordAuditEvent(ctx context.Context, event *model.AuditEvent) {
+	// the event is recorded even if the client has gone away
+	ctx = detachedContext{ctx}
+
+	err := s.store.InsertAuditEvent(ctx, event)
+
+	for _, delay := range auditRetryDelays {
+		if err == nil {
+			return
+		}
+
+		time.Sleep(delay)
+
+		err = s.store.InsertAuditEvent(ctx, event)
+	}
+
+	if err != nil {
+		s.loggerFor(ctx).Error().
+			Err(err).
+			Interface("event", event).
+			Msg("failed to record audit event, the audit log is missing this event")
+	}
+}
+
+func (s *Service) listAuditEvents(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var filter storage.AuditEventFilter
+
+	if value := query.Get("address"); value != "" {
+		address, err := storage.NormalizeAddress(value)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "invalid address")
+			return
+		}
+
+		filter.Address = address
+	}
+
+	if value := query.Get("publicKey"); value != "" {
+		publicKey, err := NormalizePublicKey(value)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		filter.PublicKey = publicKey
+	}
+
+	filter.Type = query.Get("type")
+
+	limit, err := parseListLimit(query)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	afterID, err := storage.DecodeIDCursor(query.Get("cursor"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid cursor")
+		return
+	}
+
+	page, err := s.store.ListAuditEvents(r.Context(), filter, afterID, limit)
+	if err != nil {
+		s.loggerFor(r.Context()).Error().Err(err).Msg("failed to list audit events")
+
+		respondWithError(
+			w,
+			http.StatusInternalServerError,
+			"failed to list audit events",
+		)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, page)
+}
@@ -0,0 +1,234 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/onflow/flow-account-api/model"
+	"github.com/onflow/flow-account-api/proto/accountpb"
+	"github.com/onflow/flow-account-api/storage"
+)
+
+// GRPCService serves the account API over gRPC.
+//
+// It shares its accounts, store and metrics with the HTTP service so that
+// both APIs observe the same account limit and registry.
+type GRPCService struct {
+	accountpb.UnimplementedAccountServiceServer
+	grpcServer *grpc.Server
+	port       int
+	service    *Service
+}
+
+// NewGRPCService creates a new gRPC service backed by the given HTTP service.
+func NewGRPCService(port int, service *Service) *GRPCService {
+	g := &GRPCService{
+		grpcServer: grpc.NewServer(grpc.ChainUnaryInterceptor(
+			traceUnaryInterceptor,
+			service.requestLogUnaryInterceptor,
+		)),
+		port:    port,
+		service: service,
+	}
+
+	accountpb.RegisterAccountServiceServer(g.grpcServer, g)
+
+	return g
+}
+
+func (g *GRPCService) Start() error {
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", g.port))
+	if err != nil {
+		return err
+	}
+
+	err = g.grpcServer.Serve(lis)
+	if errors.Is(err, grpc.ErrServerStopped) {
+		return nil
+	}
+
+	return err
+}
+
+// Stop waits for the calls in flight to finish, for up to the shutdown timeout of the service,
+// and then closes any connections that remain.
+func (g *GRPCService) Stop() {
+	stopped := make(chan struct{})
+
+	go func() {
+		g.grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
+	timer := time.NewTimer(g.service.shutdownTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-stopped:
+	case <-timer.C:
+		g.service.logger.Warn().Msg("gRPC calls still in flight after the shutdown timeout, closing their connections")
+
+		g.grpcServer.Stop()
+		<-stopped
+	}
+}
+
+func (g *GRPCService) CreateAccount(
+	ctx context.Context,
+	req *accountpb.CreateAccountRequest,
+) (*accountpb.CreateAccountResponse, error) {
+	account, err := g.service.CreateAccount(
+		ctx,
+		req.GetPublicKey(),
+		req.GetSignatureAlgorithm(),
+		req.GetHashAlgorithm(),
+		clientMetadataFromContext(ctx),
+	)
+	if err != nil {
+		if errors.Is(err, ErrAccountLimitExceeded) {
+			return nil, status.Error(codes.ResourceExhausted, err.Error())
+		}
+
+		if errors.Is(err, ErrShuttingDown) {
+			return nil, status.Error(codes.Unavailable, err.Error())
+		}
+
+		if isInvalidAccountKey(err) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+
+		if errors.Is(err, storage.ErrExists) {
+			return nil, status.Error(codes.AlreadyExists, "account with address or public key already exists")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to create account")
+	}
+
+	return &accountpb.CreateAccountResponse{Account: toProtoAccount(account)}, nil
+}
+
+func (g *GRPCService) GetAccountByPublicKey(
+	ctx context.Context,
+	req *accountpb.GetAccountByPublicKeyRequest,
+) (*accountpb.GetAccountByPublicKeyResponse, error) {
+	if req.GetPublicKey() == "" {
+		return nil, status.Error(codes.InvalidArgument, "publicKey is required")
+	}
+
+	publicKey, err := NormalizePublicKey(req.GetPublicKey())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	accounts, err := g.service.store.GetAccountsByPublicKey(ctx, publicKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, status.Errorf(codes.NotFound, "account with public key %s does not exist", publicKey)
+		}
+
+		g.service.loggerFor(ctx).Error().Err(err).Msg("failed to get account by public key")
+
+		return nil, status.Error(codes.Internal, "failed to get account by public key")
+	}
+
+	res := &accountpb.GetAccountByPublicKeyResponse{
+		Account:  toProtoAccount(accounts[0]),
+		Accounts: make([]*accountpb.Account, len(accounts)),
+	}
+
+	for i, account := range accounts {
+		res.Accounts[i] = toProtoAccount(account)
+	}
+
+	return res, nil
+}
+
+func (g *GRPCService) GetAccountByAddress(
+	ctx context.Context,
+	req *accountpb.GetAccountByAddressRequest,
+) (*accountpb.GetAccountByAddressResponse, error) {
+	address := req.GetAddress()
+
+	var account model.Account
+
+	err := g.service.store.GetAccountByAddress(ctx, address, &account)
+	if err != nil {
+		if errors.Is(err, storage.ErrInvalidAddress) {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid address %s", address)
+		}
+
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, status.Errorf(codes.NotFound, "account with address %s does not exist", address)
+		}
+
+		g.service.loggerFor(ctx).Error().Err(err).Msg("failed to get account by address")
+
+		return nil, status.Error(codes.Internal, "failed to get account by address")
+	}
+
+	return &accountpb.GetAccountByAddressResponse{Account: toProtoAccount(&account)}, nil
+}
+
+// clientMetadataFromContext describes the API client of a gRPC call,
+// identified by metadata that mirrors the HTTP client ID header.
+func clientMetadataFromContext(ctx context.Context) model.ClientMetadata {
+	var client model.ClientMetadata
+
+	if p, ok := peer.FromContext(ctx); ok {
+		client.IP = p.Addr.String()
+		if host, _, err := net.SplitHostPort(client.IP); err == nil {
+			client.IP = host
+		}
+	}
+
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return client
+	}
+
+	if values := md.Get(clientIDHeader); len(values) > 0 {
+		client.ID = values[0]
+	}
+
+	if values := md.Get("user-agent"); len(values) > 0 {
+		client.UserAgent = values[0]
+	}
+
+	return client
+}
+
+func toProtoAccount(account *model.Account) *accountpb.Account {
+	publicKeys := make([]*accountpb.AccountPublicKey, len(account.PublicKeys))
+
+	for i, publicKey := range account.PublicKeys {
+		publicKeys[i] = &accountpb.AccountPublicKey{
+			PublicKey:          publicKey.PublicKey,
+			SignatureAlgorithm: publicKey.SigAlgo,
+			HashAlgorithm:      publicKey.HashAlgo,
+		}
+	}
+
+	return &accountpb.Account{
+		Address:             account.Address,
+		LockedAddress:       account.LockedAddress,
+		CreationTxId:        account.CreationTransactionID,
+		PublicKeys:          publicKeys,
+		CreatedAt:           timestamppb.New(account.CreatedAt),
+		UpdatedAt:           timestamppb.New(account.UpdatedAt),
+		CreationBlockHeight: account.CreationBlockHeight,
+		Network:             account.Network,
+		CreatorKeyIndex:     uint32(account.CreatorKeyIndex),
+		ClientId:            account.ClientID,
+		ClientUserAgent:     account.ClientUserAgent,
+	}
+}
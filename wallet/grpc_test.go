@@ -0,0 +1,480 @@
+package wallet
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/onflow/flow-go-sdk/crypto"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/onflow/flow-account-api/model"
+	"github.com/onflow/flow-account-api/proto/accountpb"
+	"github.com/onflow/flow-account-api/storage/memory"
+)
+
+// testMetrics is shared by the services under test, since collectors are registered globally.
+var testMetrics = NewAccountsCollector("wallet_test")
+
+// newTestService returns a service backed by an in-memory store that holds the given accounts.
+//
+// The service has no access API client, so only requests that are answered
+// before an account would be created on chain can be made.
+func newTestService(t *testing.T, accountLimit int, accounts ...*model.Account) *Service {
+	t.Helper()
+
+	store := memory.NewStore()
+
+	for _, account := range accounts {
+		err := store.InsertAccount(context.Background(), account, nil)
+		if err != nil {
+			t.Fatalf("failed to insert account: %v", err)
+		}
+	}
+
+	return NewService(
+		ServerConfig{},
+		zerolog.Nop(),
+		&Accounts{accountLimit: accountLimit, network: "emulator", metrics: testMetrics, logger: zerolog.Nop()},
+		store,
+		testMetrics,
+		nil,
+		time.Second,
+		false,
+	)
+}
+
+// testPublicKey returns a valid ECDSA_P256 public key in its canonical encoding.
+func testPublicKey(t *testing.T) string {
+	t.Helper()
+
+	return hex.EncodeToString(generatePublicKey(t, crypto.ECDSA_P256).Encode())
+}
+
+// TestAPIParity checks that the HTTP and gRPC APIs answer the same requests alike:
+// each scenario is sent to both, and the HTTP status, gRPC code and error messages are compared.
+func TestAPIParity(t *testing.T) {
+	publicKey := testPublicKey(t)
+
+	existing := &model.Account{
+		Address: "0000000000000001",
+		PublicKeys: []*model.AccountPublicKey{
+			{PublicKey: publicKey, SigAlgo: "ECDSA_P256", HashAlgo: "SHA3_256"},
+		},
+	}
+
+	createBody := func(publicKey, sigAlgo, hashAlgo string) string {
+		body, _ := json.Marshal(createAccountRequest{PublicKey: publicKey, SigAlgo: sigAlgo, HashAlgo: hashAlgo})
+		return string(body)
+	}
+
+	tests := []struct {
+		name         string
+		accountLimit int
+		accounts     []*model.Account
+		method       string
+		path         string
+		body         string
+		call         func(ctx context.Context, g *GRPCService) error
+		wantStatus   int
+		wantCode     codes.Code
+	}{
+		{
+			name:     "GetAccountByAddress",
+			accounts: []*model.Account{existing},
+			method:   http.MethodGet,
+			path:     "/accounts/0x01",
+			call: func(ctx context.Context, g *GRPCService) error {
+				_, err := g.GetAccountByAddress(ctx, &accountpb.GetAccountByAddressRequest{Address: "0x01"})
+				return err
+			},
+			wantStatus: http.StatusOK,
+			wantCode:   codes.OK,
+		},
+		{
+			name:   "GetAccountByAddressNotFound",
+			method: http.MethodGet,
+			path:   "/accounts/0x01",
+			call: func(ctx context.Context, g *GRPCService) error {
+				_, err := g.GetAccountByAddress(ctx, &accountpb.GetAccountByAddressRequest{Address: "0x01"})
+				return err
+			},
+			wantStatus: http.StatusNotFound,
+			wantCode:   codes.NotFound,
+		},
+		{
+			name:   "GetAccountByAddressInvalidAddress",
+			method: http.MethodGet,
+			path:   "/accounts/0xzz",
+			call: func(ctx context.Context, g *GRPCService) error {
+				_, err := g.GetAccountByAddress(ctx, &accountpb.GetAccountByAddressRequest{Address: "0xzz"})
+				return err
+			},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   codes.InvalidArgument,
+		},
+		{
+			name:     "GetAccountByPublicKey",
+			accounts: []*model.Account{existing},
+			method:   http.MethodGet,
+			path:     "/accounts?publicKey=0x" + strings.ToUpper(publicKey),
+			call: func(ctx context.Context, g *GRPCService) error {
+				_, err := g.GetAccountByPublicKey(ctx, &accountpb.GetAccountByPublicKeyRequest{PublicKey: "0x" + strings.ToUpper(publicKey)})
+				return err
+			},
+			wantStatus: http.StatusOK,
+			wantCode:   codes.OK,
+		},
+		{
+			name:   "GetAccountByPublicKeyNotFound",
+			method: http.MethodGet,
+			path:   "/accounts?publicKey=" + publicKey,
+			call: func(ctx context.Context, g *GRPCService) error {
+				_, err := g.GetAccountByPublicKey(ctx, &accountpb.GetAccountByPublicKeyRequest{PublicKey: publicKey})
+				return err
+			},
+			wantStatus: http.StatusNotFound,
+			wantCode:   codes.NotFound,
+		},
+		{
+			name:   "GetAccountByPublicKeyInvalidKey",
+			method: http.MethodGet,
+			path:   "/accounts?publicKey=" + publicKey[:64],
+			call: func(ctx context.Context, g *GRPCService) error {
+				_, err := g.GetAccountByPublicKey(ctx, &accountpb.GetAccountByPublicKeyRequest{PublicKey: publicKey[:64]})
+				return err
+			},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   codes.InvalidArgument,
+		},
+		{
+			name:   "CreateAccountInvalidKey",
+			method: http.MethodPost,
+			path:   "/accounts",
+			body:   createBody(publicKey[:64], "ECDSA_P256", "SHA3_256"),
+			call: func(ctx context.Context, g *GRPCService) error {
+				_, err := g.CreateAccount(ctx, &accountpb.CreateAccountRequest{
+					PublicKey:          publicKey[:64],
+					SignatureAlgorithm: "ECDSA_P256",
+					HashAlgorithm:      "SHA3_256",
+				})
+				return err
+			},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   codes.InvalidArgument,
+		},
+		{
+			name:   "CreateAccountInvalidHashAlgorithm",
+			method: http.MethodPost,
+			path:   "/accounts",
+			body:   createBody(publicKey, "ECDSA_P256", "SHA3_384"),
+			call: func(ctx context.Context, g *GRPCService) error {
+				_, err := g.CreateAccount(ctx, &accountpb.CreateAccountRequest{
+					PublicKey:          publicKey,
+					SignatureAlgorithm: "ECDSA_P256",
+					HashAlgorithm:      "SHA3_384",
+				})
+				return err
+			},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   codes.InvalidArgument,
+		},
+		{
+			name:         "CreateAccountLimitReached",
+			accountLimit: 1,
+			accounts:     []*model.Account{existing},
+			method:       http.MethodPost,
+			path:         "/accounts",
+			body:         createBody(publicKey, "ECDSA_P256", "SHA3_256"),
+			call: func(ctx context.Context, g *GRPCService) error {
+				_, err := g.CreateAccount(ctx, &accountpb.CreateAccountRequest{
+					PublicKey:          publicKey,
+					SignatureAlgorithm: "ECDSA_P256",
+					HashAlgorithm:      "SHA3_256",
+				})
+				return err
+			},
+			wantStatus: http.StatusForbidden,
+			wantCode:   codes.ResourceExhausted,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			// each API gets its own service, so that neither sees the other's side effects
+			httpService := newTestService(t, test.accountLimit, copyAccounts(test.accounts)...)
+			grpcService := NewGRPCService(0, newTestService(t, test.accountLimit, copyAccounts(test.accounts)...))
+
+			req := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+			rec := httptest.NewRecorder()
+
+			httpService.httpServer.Handler.ServeHTTP(rec, req)
+
+			if rec.Code != test.wantStatus {
+				t.Errorf("got HTTP status %d, want %d: %s", rec.Code, test.wantStatus, rec.Body)
+			}
+
+			err := test.call(context.Background(), grpcService)
+
+			if code := status.Code(err); code != test.wantCode {
+				t.Errorf("got gRPC code %s, want %s: %v", code, test.wantCode, err)
+			}
+
+			if test.wantCode == codes.OK {
+				return
+			}
+
+			var body struct {
+				Error string `json:"error"`
+			}
+
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("failed to decode HTTP error response %q: %v", rec.Body, err)
+			}
+
+			if message := status.Convert(err).Message(); body.Error != message {
+				t.Errorf("got HTTP error %q and gRPC error %q, want the same message", body.Error, message)
+			}
+		})
+	}
+}
+
+// apiAccount holds the account fields that both APIs return, for comparing their responses.
+type apiAccount struct {
+	Address             string
+	LockedAddress       string
+	CreationTxID        string
+	PublicKeys          []model.AccountPublicKey
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	CreationBlockHeight uint64
+	Network             string
+	CreatorKeyIndex     int
+	ClientID            string
+	ClientUserAgent     string
+}
+
+func apiAccountFromModel(account *model.Account) apiAccount {
+	a := apiAccount{
+		Address:             account.Address,
+		LockedAddress:       account.LockedAddress,
+		CreationTxID:        account.CreationTransactionID,
+		CreatedAt:           account.CreatedAt.UTC(),
+		UpdatedAt:           account.UpdatedAt.UTC(),
+		CreationBlockHeight: account.CreationBlockHeight,
+		Network:             account.Network,
+		CreatorKeyIndex:     account.CreatorKeyIndex,
+		ClientID:            account.ClientID,
+		ClientUserAgent:     account.ClientUserAgent,
+	}
+
+	for _, publicKey := range account.PublicKeys {
+		a.PublicKeys = append(a.PublicKeys, model.AccountPublicKey{
+			PublicKey: publicKey.PublicKey,
+			SigAlgo:   publicKey.SigAlgo,
+			HashAlgo:  publicKey.HashAlgo,
+		})
+	}
+
+	return a
+}
+
+func apiAccountFromProto(account *accountpb.Account) apiAccount {
+	a := apiAccount{
+		Address:             account.GetAddress(),
+		LockedAddress:       account.GetLockedAddress(),
+		CreationTxID:        account.GetCreationTxId(),
+		CreatedAt:           account.GetCreatedAt().AsTime(),
+		UpdatedAt:           account.GetUpdatedAt().AsTime(),
+		CreationBlockHeight: account.GetCreationBlockHeight(),
+		Network:             account.GetNetwork(),
+		CreatorKeyIndex:     int(account.GetCreatorKeyIndex()),
+		ClientID:            account.GetClientId(),
+		ClientUserAgent:     account.GetClientUserAgent(),
+	}
+
+	for _, publicKey := range account.GetPublicKeys() {
+		a.PublicKeys = append(a.PublicKeys, model.AccountPublicKey{
+			PublicKey: publicKey.GetPublicKey(),
+			SigAlgo:   publicKey.GetSignatureAlgorithm(),
+			HashAlgo:  publicKey.GetHashAlgorithm(),
+		})
+	}
+
+	return a
+}
+
+// TestAPIParityResponses checks that successful responses of the HTTP and gRPC APIs carry the same accounts.
+func TestAPIParityResponses(t *testing.T) {
+	sharedKey := testPublicKey(t)
+	otherKey := hex.EncodeToString(generatePublicKey(t, crypto.ECDSA_secp256k1).Encode())
+
+	createdAt := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	accounts := []*model.Account{
+		{
+			Address:               "0000000000000002",
+			LockedAddress:         "0000000000000012",
+			CreationTransactionID: "a0d1de2ba4d4d85a9c8e6ff4b1dcf8e2b0fb0a5a8f6a53c1f2c5f1ce4b4e1c11",
+			PublicKeys: []*model.AccountPublicKey{
+				{PublicKey: sharedKey, SigAlgo: "ECDSA_P256", HashAlgo: "SHA3_256"},
+				{PublicKey: otherKey, SigAlgo: "ECDSA_secp256k1", HashAlgo: "SHA2_256"},
+			},
+			CreatedAt:           createdAt,
+			UpdatedAt:           createdAt.Add(time.Minute),
+			CreationBlockHeight: 42,
+			Network:             "emulator",
+			CreatorKeyIndex:     3,
+			ClientID:            "wallet-app",
+			ClientIP:            "192.0.2.1",
+			ClientUserAgent:     "wallet-app/1.0",
+		},
+		{
+			Address: "0000000000000001",
+			PublicKeys: []*model.AccountPublicKey{
+				{PublicKey: sharedKey, SigAlgo: "ECDSA_P256", HashAlgo: "SHA3_256"},
+			},
+			CreatedAt: createdAt.Add(time.Hour),
+			UpdatedAt: createdAt.Add(time.Hour),
+		},
+	}
+
+	getJSON := func(t *testing.T, s *Service, path string, v interface{}) {
+		t.Helper()
+
+		rec := httptest.NewRecorder()
+		s.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("got HTTP status %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+		}
+
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+			t.Fatalf("failed to decode HTTP response %q: %v", rec.Body, err)
+		}
+	}
+
+	compare := func(t *testing.T, httpAccounts []*model.Account, grpcAccounts []*accountpb.Account) {
+		t.Helper()
+
+		if len(httpAccounts) != len(grpcAccounts) {
+			t.Fatalf("got %d HTTP accounts and %d gRPC accounts, want the same", len(httpAccounts), len(grpcAccounts))
+		}
+
+		for i := range httpAccounts {
+			h, g := apiAccountFromModel(httpAccounts[i]), apiAccountFromProto(grpcAccounts[i])
+
+			if !reflect.DeepEqual(h, g) {
+				t.Errorf("accounts differ:\nHTTP %+v\ngRPC %+v", h, g)
+			}
+		}
+	}
+
+	t.Run("GetAccountByAddress", func(t *testing.T) {
+		httpService := newTestService(t, 0, copyAccounts(accounts)...)
+		grpcService := NewGRPCService(0, newTestService(t, 0, copyAccounts(accounts)...))
+
+		var httpAccount model.Account
+		getJSON(t, httpService, "/accounts/0x02", &httpAccount)
+
+		res, err := grpcService.GetAccountByAddress(context.Background(), &accountpb.GetAccountByAddressRequest{Address: "0x02"})
+		if err != nil {
+			t.Fatalf("failed to get account over gRPC: %v", err)
+		}
+
+		compare(t, []*model.Account{&httpAccount}, []*accountpb.Account{res.GetAccount()})
+
+		if got := apiAccountFromProto(res.GetAccount()); !reflect.DeepEqual(got, apiAccountFromModel(accounts[0])) {
+			t.Errorf("got account %+v, want the stored account", got)
+		}
+	})
+
+	// HTTP responds with every account sharing the key, or only the first with single account lookups,
+	// while gRPC always responds with both
+	t.Run("GetAccountByPublicKey", func(t *testing.T) {
+		httpService := newTestService(t, 0, copyAccounts(accounts)...)
+		grpcService := NewGRPCService(0, newTestService(t, 0, copyAccounts(accounts)...))
+
+		var httpAccounts []*model.Account
+		getJSON(t, httpService, "/accounts?publicKey="+sharedKey, &httpAccounts)
+
+		res, err := grpcService.GetAccountByPublicKey(context.Background(), &accountpb.GetAccountByPublicKeyRequest{PublicKey: sharedKey})
+		if err != nil {
+			t.Fatalf("failed to get accounts over gRPC: %v", err)
+		}
+
+		compare(t, httpAccounts, res.GetAccounts())
+
+		if len(httpAccounts) != 2 || httpAccounts[0].Address != "0000000000000001" {
+			t.Errorf("got accounts %v, want both accounts ordered by address", httpAccounts)
+		}
+
+		if len(res.GetAccounts()) > 0 && !reflect.DeepEqual(apiAccountFromProto(res.GetAccount()), apiAccountFromProto(res.GetAccounts()[0])) {
+			t.Error("gRPC account is not the first of the accounts")
+		}
+	})
+
+	t.Run("GetAccountByPublicKeySingleAccountLookup", func(t *testing.T) {
+		httpService := newTestService(t, 0, copyAccounts(accounts)...)
+		httpService.singleAccountLookup = true
+
+		grpcService := NewGRPCService(0, newTestService(t, 0, copyAccounts(accounts)...))
+
+		var httpAccount model.Account
+		getJSON(t, httpService, "/accounts?publicKey="+sharedKey, &httpAccount)
+
+		res, err := grpcService.GetAccountByPublicKey(context.Background(), &accountpb.GetAccountByPublicKeyRequest{PublicKey: sharedKey})
+		if err != nil {
+			t.Fatalf("failed to get accounts over gRPC: %v", err)
+		}
+
+		compare(t, []*model.Account{&httpAccount}, []*accountpb.Account{res.GetAccount()})
+	})
+
+	// creating an account needs an access node, so compare the encodings both create endpoints
+	// respond with for the account they created instead
+	t.Run("CreateAccount", func(t *testing.T) {
+		account := copyAccounts(accounts[:1])[0]
+
+		rec := httptest.NewRecorder()
+		respondWithJSON(rec, http.StatusCreated, &account)
+
+		var httpAccount model.Account
+		if err := json.Unmarshal(rec.Body.Bytes(), &httpAccount); err != nil {
+			t.Fatalf("failed to decode HTTP response %q: %v", rec.Body, err)
+		}
+
+		res := &accountpb.CreateAccountResponse{Account: toProtoAccount(account)}
+
+		compare(t, []*model.Account{&httpAccount}, []*accountpb.Account{res.GetAccount()})
+	})
+}
+
+// copyAccounts returns deep copies of accounts, since stores take ownership of the accounts they insert.
+func copyAccounts(accounts []*model.Account) []*model.Account {
+	copies := make([]*model.Account, len(accounts))
+
+	for i, account := range accounts {
+		c := *account
+		c.PublicKeys = make([]*model.AccountPublicKey, len(account.PublicKeys))
+
+		for j, publicKey := range account.PublicKeys {
+			k := *publicKey
+			c.PublicKeys[j] = &k
+		}
+
+		copies[i] = &c
+	}
+
+	return copies
+}
@@ -0,0 +1,151 @@
+package wallet
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// readinessCheckTimeout bounds each dependency check so that a hanging dependency
+// fails the check rather than the probe.
+const readinessCheckTimeout = 5 * time.Second
+
+const (
+	checkStatusOK   = "ok"
+	checkStatusFail = "fail"
+)
+
+// readinessCheck checks one dependency of the service.
+//
+// A failing critical check makes the service unready. Other checks are reported
+// but do not take the service out of rotation, since it can still serve lookups.
+type readinessCheck struct {
+	name     string
+	critical bool
+	check    func(ctx context.Context) error
+}
+
+type checkResult struct {
+	Status    string  `json:"status"`
+	Critical  bool    `json:"critical"`
+	LatencyMs float64 `json:"latencyMs"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type readinessResponse struct {
+	Status string                  `json:"status"`
+	Checks map[string]*checkResult `json:"checks"`
+}
+
+func (s *Service) readinessChecks() []readinessCheck {
+	return []readinessCheck{
+		{name: "store", critical: true, check: s.store.Ping},
+		{name: "accessAPI", critical: true, check: s.accounts.Ping},
+		{name: "creatorKey", critical: true, check: s.accounts.CheckCreatorKey},
+		{name: "accountLimit", critical: false, check: s.checkAccountLimit},
+	}
+}
+
+// livenessCheck reports that the process is running, without checking any dependencies.
+func livenessCheck(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, map[string]string{"status": checkStatusOK})
+}
+
+// readinessCheck runs every dependency check concurrently and reports the result of each.
+//
+// A service that is shutting down is unready, so that it is taken out of rotation while it drains.
+func (s *Service) readinessCheck(w http.ResponseWriter, r *http.Request) {
+	if s.creations.isDraining() {
+		respondWithJSON(w, http.StatusServiceUnavailable, &readinessResponse{
+			Status: checkStatusFail,
+			Checks: map[string]*checkResult{},
+		})
+		return
+	}
+
+	checks := s.readinessChecks()
+
+	res := &readinessResponse{
+		Status: checkStatusOK,
+		Checks: make(map[string]*checkResult, len(checks)),
+	}
+
+	results := make([]*checkResult, len(checks))
+
+	var wg sync.WaitGroup
+
+	for i, c := range checks {
+		wg.Add(1)
+
+		go func(i int, c readinessCheck) {
+			defer wg.Done()
+			results[i] = runCheck(r.Context(), c)
+		}(i, c)
+	}
+
+	wg.Wait()
+
+	code := http.StatusOK
+
+	for i, c := range checks {
+		result := results[i]
+		res.Checks[c.name] = result
+
+		if result.Status != checkStatusOK {
+			s.loggerFor(r.Context()).Warn().Str("check", c.name).Str("error", result.Error).Msg("readiness check failed")
+
+			if c.critical {
+				res.Status = checkStatusFail
+				code = http.StatusServiceUnavailable
+			}
+		}
+	}
+
+	respondWithJSON(w, code, res)
+}
+
+func runCheck(ctx context.Context, c readinessCheck) *checkResult {
+	ctx, cancel := context.WithTimeout(ctx, readinessCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := c.check(ctx)
+	latency := time.Since(start)
+
+	result := &checkResult{
+		Status:    checkStatusOK,
+		Critical:  c.critical,
+		LatencyMs: float64(latency) / float64(time.Millisecond),
+	}
+
+	if err != nil {
+		result.Status = checkStatusFail
+		result.Error = err.Error()
+	}
+
+	return result
+}
+
+// checkAccountLimit fails once the service has created as many accounts as it is allowed to.
+//
+// The service has no paused state, so the account limit is the only condition
+// under which it stops creating accounts.
+func (s *Service) checkAccountLimit(ctx context.Context) error {
+	maxAccounts := s.accounts.GetLimit()
+	if maxAccounts == 0 {
+		return nil
+	}
+
+	numAccounts, err := s.store.GetAccountCount(ctx)
+	if err != nil {
+		return err
+	}
+
+	if numAccounts >= maxAccounts {
+		return fmt.Errorf("account limit of %d reached", maxAccounts)
+	}
+
+	return nil
+}
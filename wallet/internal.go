@@ -0,0 +1,94 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// InternalServer serves health checks, metrics, profiling and admin routes.
+//
+// It is bound to an internal address so that none of these are exposed
+// alongside the public account API.
+type InternalServer struct {
+	httpServer *http.Server
+	service    *Service
+}
+
+// NewInternalServer creates an internal server for the given service.
+func NewInternalServer(conf ServerConfig, service *Service) *InternalServer {
+	router := mux.NewRouter()
+
+	router.Use(traceHandler, service.requestLogHandler, service.metrics.instrumentHandler)
+
+	router.
+		Handle("/metrics", promhttp.Handler()).
+		Methods(http.MethodGet)
+
+	router.
+		HandleFunc("/health", healthCheck)
+
+	router.
+		HandleFunc("/livez", livenessCheck).
+		Methods(http.MethodGet)
+
+	router.
+		HandleFunc("/readyz", service.readinessCheck).
+		Methods(http.MethodGet)
+
+	router.
+		HandleFunc("/accounts/list", service.listAccounts).
+		Methods(http.MethodGet)
+
+	router.
+		HandleFunc("/audit/events", service.listAuditEvents).
+		Methods(http.MethodGet)
+
+	router.
+		HandleFunc("/webhooks/dead-letters", service.listDeadWebhookDeliveries).
+		Methods(http.MethodGet)
+
+	router.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	router.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	router.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	router.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	// the index also serves the named profiles, such as /debug/pprof/heap
+	router.PathPrefix("/debug/pprof/").HandlerFunc(pprof.Index)
+
+	return &InternalServer{
+		httpServer: conf.newServer(conf.InternalAddress, router),
+		service:    service,
+	}
+}
+
+// movedToInternalServer responds to public requests for routes that are now only served by the internal server.
+func movedToInternalServer(w http.ResponseWriter, r *http.Request) {
+	respondWithError(
+		w,
+		http.StatusGone,
+		fmt.Sprintf("%s is no longer served by the public API, use the internal server instead", r.URL.Path),
+	)
+}
+
+func (i *InternalServer) Start() error {
+	err := i.httpServer.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+
+	return err
+}
+
+// Stop waits for the requests in flight to finish, for up to the shutdown timeout of the service.
+func (i *InternalServer) Stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), i.service.shutdownTimeout)
+	defer cancel()
+
+	_ = i.httpServer.Shutdown(ctx)
+}
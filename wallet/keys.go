@@ -0,0 +1,128 @@
+package wallet
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/onflow/flow-go-sdk/crypto"
+)
+
+// uncompressedPointPrefix marks an ECDSA public key encoded as an uncompressed curve point.
+const uncompressedPointPrefix = 0x04
+
+// decodePublicKey decodes a public key for the given signature algorithm.
+//
+// The key may be hex encoded, with or without a 0x prefix and in any case, or base64 encoded.
+// Keys encoded as uncompressed curve points (prefixed with 04) are also accepted.
+//
+// The returned error wraps errInvalidPublicKey and names the problem with the key.
+func decodePublicKey(sigAlgo crypto.SignatureAlgorithm, encodedPublicKey string) (crypto.PublicKey, error) {
+	b, err := decodePublicKeyBytes(encodedPublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// raw keys consist of two coordinates of equal length, so an odd length means a prefixed point
+	if len(b)%2 == 1 && b[0] == uncompressedPointPrefix {
+		b = b[1:]
+	}
+
+	if length, ok := publicKeyLengths[sigAlgo]; ok && len(b) != length {
+		if len(b) == length/2+1 && (b[0] == 0x02 || b[0] == 0x03) {
+			return nil, fmt.Errorf("%w: compressed %s keys are not supported, the key must be an uncompressed curve point", errInvalidPublicKey, sigAlgo)
+		}
+
+		return nil, fmt.Errorf("%w: %s keys must be %d bytes, got %d", errInvalidPublicKey, sigAlgo, length, len(b))
+	}
+
+	publicKey, err := crypto.DecodePublicKeyHex(sigAlgo, hex.EncodeToString(b))
+	if err != nil {
+		return nil, &curveError{sigAlgos: []crypto.SignatureAlgorithm{sigAlgo}}
+	}
+
+	return publicKey, nil
+}
+
+// publicKeyLengths are the lengths in bytes of the raw public keys of each signature algorithm,
+// two coordinates of the curve point.
+var publicKeyLengths = map[crypto.SignatureAlgorithm]int{
+	crypto.ECDSA_P256:      64,
+	crypto.ECDSA_secp256k1: 64,
+}
+
+// NormalizePublicKey converts a public key in any of the encodings accepted by decodePublicKey
+// to the canonical encoding used by the store.
+//
+// The signature algorithm of the key is not known when looking up accounts, so the key is
+// accepted if it is valid for any of the supported algorithms.
+func NormalizePublicKey(encodedPublicKey string) (string, error) {
+	notOnCurve := &curveError{}
+
+	for _, sigAlgo := range accountKeySigAlgos() {
+		publicKey, err := decodePublicKey(sigAlgo, encodedPublicKey)
+		if err == nil {
+			return encodePublicKey(publicKey), nil
+		}
+
+		// problems with the encoding or length of a key do not depend on the algorithm
+		if !errors.As(err, new(*curveError)) {
+			return "", err
+		}
+
+		notOnCurve.sigAlgos = append(notOnCurve.sigAlgos, sigAlgo)
+	}
+
+	return "", notOnCurve
+}
+
+// curveError is returned for a public key that is not a point on the curve of any of the given algorithms.
+type curveError struct {
+	sigAlgos []crypto.SignatureAlgorithm
+}
+
+func (e *curveError) Error() string {
+	curves := make([]string, len(e.sigAlgos))
+	for i, sigAlgo := range e.sigAlgos {
+		curves[i] = sigAlgo.String()
+	}
+
+	return fmt.Sprintf("%s: the key is not a point on the %s curve", errInvalidPublicKey, strings.Join(curves, " or "))
+}
+
+func (e *curveError) Unwrap() error {
+	return errInvalidPublicKey
+}
+
+// encodePublicKey returns the canonical encoding of a public key:
+// the lowercase hex of the raw curve point, without a prefix.
+func encodePublicKey(publicKey crypto.PublicKey) string {
+	return hex.EncodeToString(publicKey.Encode())
+}
+
+func decodePublicKeyBytes(encodedPublicKey string) ([]byte, error) {
+	encodedPublicKey = strings.TrimSpace(encodedPublicKey)
+
+	if strings.HasPrefix(encodedPublicKey, "0x") || strings.HasPrefix(encodedPublicKey, "0X") {
+		b, err := hex.DecodeString(encodedPublicKey[2:])
+		if err != nil {
+			return nil, fmt.Errorf("%w: the key has a 0x prefix but is not hex encoded", errInvalidPublicKey)
+		}
+
+		return b, nil
+	}
+
+	if b, err := hex.DecodeString(encodedPublicKey); err == nil {
+		return b, nil
+	}
+
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if b, err := encoding.DecodeString(encodedPublicKey); err == nil && len(b) > 0 {
+			return b, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: the key is neither hex nor base64 encoded", errInvalidPublicKey)
+}
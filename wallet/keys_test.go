@@ -0,0 +1,234 @@
+package wallet
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/onflow/flow-go-sdk/crypto"
+)
+
+// generatePublicKey returns a public key for the signature algorithm, derived from a fixed seed.
+func generatePublicKey(t *testing.T, sigAlgo crypto.SignatureAlgorithm) crypto.PublicKey {
+	t.Helper()
+
+	seed := make([]byte, crypto.MinSeedLength)
+	for i := range seed {
+		seed[i] = byte(i)
+	}
+
+	privateKey, err := crypto.GeneratePrivateKey(sigAlgo, seed)
+	if err != nil {
+		t.Fatalf("failed to generate %s key: %v", sigAlgo, err)
+	}
+
+	return privateKey.PublicKey()
+}
+
+func TestDecodePublicKey(t *testing.T) {
+	publicKey := generatePublicKey(t, crypto.ECDSA_P256)
+	raw := publicKey.Encode()
+	canonical := hex.EncodeToString(raw)
+
+	// every coordinate is 1, which is not a point on the curve
+	offCurve := strings.Repeat("01", 64)
+
+	tests := []struct {
+		name    string
+		key     string
+		wantErr string
+	}{
+		{name: "Hex", key: canonical},
+		{name: "UppercaseHex", key: strings.ToUpper(canonical)},
+		{name: "HexWith0xPrefix", key: "0x" + canonical},
+		{name: "HexWithUppercase0XPrefix", key: "0X" + canonical},
+		{name: "HexWithSurroundingSpace", key: " " + canonical + "\n"},
+		{name: "UncompressedPointPrefix", key: "04" + canonical},
+		{name: "UncompressedPointPrefixAnd0x", key: "0x04" + canonical},
+		{name: "Base64", key: base64.StdEncoding.EncodeToString(raw)},
+		{name: "RawURLBase64", key: base64.RawURLEncoding.EncodeToString(raw)},
+		{name: "Base64UncompressedPoint", key: base64.StdEncoding.EncodeToString(append([]byte{0x04}, raw...))},
+		{
+			name:    "TooShort",
+			key:     canonical[:64],
+			wantErr: "invalid public key: ECDSA_P256 keys must be 64 bytes, got 32",
+		},
+		{
+			name:    "TooLong",
+			key:     canonical + "00",
+			wantErr: "invalid public key: ECDSA_P256 keys must be 64 bytes, got 65",
+		},
+		{
+			name:    "Compressed",
+			key:     "02" + canonical[:64],
+			wantErr: "invalid public key: compressed ECDSA_P256 keys are not supported, the key must be an uncompressed curve point",
+		},
+		{
+			name:    "OffCurve",
+			key:     offCurve,
+			wantErr: "invalid public key: the key is not a point on the ECDSA_P256 curve",
+		},
+		{
+			name:    "BadHexAfter0x",
+			key:     "0x" + canonical[:126] + "zz",
+			wantErr: "invalid public key: the key has a 0x prefix but is not hex encoded",
+		},
+		{
+			name:    "NotEncoded",
+			key:     "not a key!",
+			wantErr: "invalid public key: the key is neither hex nor base64 encoded",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			got, err := decodePublicKey(crypto.ECDSA_P256, test.key)
+
+			if test.wantErr != "" {
+				if err == nil {
+					t.Fatalf("decoded %q, want error %q", test.key, test.wantErr)
+				}
+
+				if !errors.Is(err, errInvalidPublicKey) {
+					t.Errorf("got error %v, want it to wrap %v", err, errInvalidPublicKey)
+				}
+
+				if err.Error() != test.wantErr {
+					t.Errorf("got error %q, want %q", err, test.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("failed to decode %q: %v", test.key, err)
+			}
+
+			if !got.Equals(publicKey) {
+				t.Errorf("decoded %q to %s, want %s", test.key, got, publicKey)
+			}
+		})
+	}
+}
+
+func TestNormalizePublicKey(t *testing.T) {
+	p256 := hex.EncodeToString(generatePublicKey(t, crypto.ECDSA_P256).Encode())
+	secp256k1 := hex.EncodeToString(generatePublicKey(t, crypto.ECDSA_secp256k1).Encode())
+
+	tests := []struct {
+		name    string
+		key     string
+		want    string
+		wantErr string
+	}{
+		{name: "P256", key: "0x04" + strings.ToUpper(p256), want: p256},
+		{name: "Secp256k1", key: "0x" + secp256k1, want: secp256k1},
+		{
+			name:    "WrongLength",
+			key:     p256[:64],
+			wantErr: "invalid public key: ECDSA_P256 keys must be 64 bytes, got 32",
+		},
+		{
+			name:    "OffEveryCurve",
+			key:     strings.Repeat("01", 64),
+			wantErr: "invalid public key: the key is not a point on the ECDSA_P256 or ECDSA_secp256k1 curve",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			got, err := NormalizePublicKey(test.key)
+
+			if test.wantErr != "" {
+				if !errors.Is(err, errInvalidPublicKey) || err.Error() != test.wantErr {
+					t.Errorf("got key %q and error %v, want error %q", got, err, test.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("failed to normalize %q: %v", test.key, err)
+			}
+
+			if got != test.want {
+				t.Errorf("normalized %q to %q, want %q", test.key, got, test.want)
+			}
+		})
+	}
+}
+
+func TestAccountKeyAlgorithmsFor(t *testing.T) {
+	tests := []struct {
+		name     string
+		network  string
+		sigAlgo  string
+		hashAlgo string
+		wantErr  error
+	}{
+		{name: "P256WithSHA3_256", network: "mainnet", sigAlgo: "ECDSA_P256", hashAlgo: "SHA3_256"},
+		{name: "P256WithSHA2_256", network: "testnet", sigAlgo: "ECDSA_P256", hashAlgo: "SHA2_256"},
+		{name: "Secp256k1WithSHA2_256", network: "emulator", sigAlgo: "ECDSA_secp256k1", hashAlgo: "SHA2_256"},
+		{name: "NetworkWithoutPolicy", network: "localnet", sigAlgo: "ECDSA_secp256k1", hashAlgo: "SHA3_256"},
+		{name: "BLS", network: "mainnet", sigAlgo: "BLS_BLS12381", hashAlgo: "SHA3_256", wantErr: errInvalidSigAlgo},
+		{name: "UnknownSigAlgo", network: "mainnet", sigAlgo: "ECDSA_P384", hashAlgo: "SHA3_256", wantErr: errInvalidSigAlgo},
+		{name: "P256WithSHA2_384", network: "mainnet", sigAlgo: "ECDSA_P256", hashAlgo: "SHA2_384", wantErr: errInvalidHashAlgo},
+		{name: "P256WithSHA3_384", network: "testnet", sigAlgo: "ECDSA_P256", hashAlgo: "SHA3_384", wantErr: errInvalidHashAlgo},
+		{name: "LowercaseNames", network: "mainnet", sigAlgo: "ecdsa_p256", hashAlgo: "sha3_256", wantErr: errInvalidSigAlgo},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			sigAlgo, hashAlgo, err := accountKeyAlgorithmsFor(test.network, test.sigAlgo, test.hashAlgo)
+
+			if test.wantErr != nil {
+				if !errors.Is(err, test.wantErr) {
+					t.Errorf("got error %v, want it to wrap %v", err, test.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("rejected %s with %s: %v", test.sigAlgo, test.hashAlgo, err)
+			}
+
+			if sigAlgo.String() != test.sigAlgo || hashAlgo.String() != test.hashAlgo {
+				t.Errorf("got %s with %s, want %s with %s", sigAlgo, hashAlgo, test.sigAlgo, test.hashAlgo)
+			}
+		})
+	}
+}
+
+func TestAccountKeyAlgorithmsForNarrowedNetwork(t *testing.T) {
+	policy := keyPolicies["testnet"]
+	defer func() { keyPolicies["testnet"] = policy }()
+
+	keyPolicies["testnet"] = []keyAlgorithms{{crypto.ECDSA_P256, crypto.SHA3_256}}
+
+	_, _, err := accountKeyAlgorithmsFor("testnet", "ECDSA_P256", "SHA2_256")
+
+	want := `invalid hash algorithm: "SHA2_256" is not supported for ECDSA_P256 account keys, must be one of SHA3_256`
+	if err == nil || err.Error() != want {
+		t.Errorf("got error %v on the narrowed network, want %q", err, want)
+	}
+
+	_, _, err = accountKeyAlgorithmsFor("testnet", "ECDSA_secp256k1", "SHA3_256")
+	if !errors.Is(err, errInvalidSigAlgo) {
+		t.Errorf("got error %v for an algorithm the narrowed network does not allow, want %v", err, errInvalidSigAlgo)
+	}
+
+	// other networks keep their own policies
+	_, _, err = accountKeyAlgorithmsFor("mainnet", "ECDSA_P256", "SHA2_256")
+	if err != nil {
+		t.Errorf("mainnet rejected a pair only testnet disallows: %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/onflow/flow-account-api/storage"
+)
+
+const (
+	defaultListLimit = 100
+	maxListLimit     = 1000
+)
+
+func (s *Service) listAccounts(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	filter, err := parseAccountFilter(query)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	limit, err := parseListLimit(query)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	cursor, err := storage.DecodeCursor(query.Get("cursor"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid cursor")
+		return
+	}
+
+	page, err := s.store.ListAccounts(r.Context(), filter, cursor, limit)
+	if err != nil {
+		s.loggerFor(r.Context()).Error().Err(err).Msg("failed to list accounts")
+
+		respondWithError(
+			w,
+			http.StatusInternalServerError,
+			"failed to list accounts",
+		)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, page)
+}
+
+// parseListLimit returns the page size requested by a listing, or the default if none was requested.
+func parseListLimit(query url.Values) (int, error) {
+	value := query.Get("limit")
+	if value == "" {
+		return defaultListLimit, nil
+	}
+
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 1 || limit > maxListLimit {
+		return 0, fmt.Errorf("limit must be between 1 and %d", maxListLimit)
+	}
+
+	return limit, nil
+}
+
+func parseAccountFilter(query url.Values) (storage.AccountFilter, error) {
+	var filter storage.AccountFilter
+
+	for name, field := range map[string]*time.Time{
+		"createdAfter":  &filter.CreatedAfter,
+		"createdBefore": &filter.CreatedBefore,
+	} {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return filter, fmt.Errorf("%s must be an RFC 3339 timestamp", name)
+		}
+
+		*field = t
+	}
+
+	filter.SigAlgo = query.Get("signatureAlgorithm")
+	filter.HashAlgo = query.Get("hashAlgorithm")
+	filter.ClientID = query.Get("clientId")
+
+	if value := query.Get("hasLockedAddress"); value != "" {
+		hasLockedAddress, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, errors.New("hasLockedAddress must be true or false")
+		}
+
+		filter.HasLockedAddress = &hasLockedAddress
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,174 @@
+package wallet
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/onflow/flow-account-api/pkg/logging"
+	"github.com/onflow/flow-account-api/pkg/tracing"
+)
+
+// requestIDHeader carries the ID of a request, either chosen by the client or assigned by the service.
+const requestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds the length of client-chosen request IDs, which are copied into every log line.
+const maxRequestIDLength = 128
+
+// quietRoutes are polled by monitoring and are only logged at debug level.
+var quietRoutes = map[string]bool{
+	"/metrics": true,
+	"/health":  true,
+	"/livez":   true,
+	"/readyz":  true,
+}
+
+// loggerFor returns the logger of the request being handled in the context,
+// or the service logger outside of a request.
+func (s *Service) loggerFor(ctx context.Context) *zerolog.Logger {
+	return logging.FromContext(ctx, s.logger)
+}
+
+// requestLogHandler assigns each request an ID, attaches a logger carrying it to the request context
+// and writes an access log line once the request has been handled.
+func (s *Service) requestLogHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		requestID := requestIDOrNew(r.Header.Get(requestIDHeader))
+		w.Header().Set(requestIDHeader, requestID)
+
+		ctx := s.requestContext(r.Context(), requestID)
+		logger := s.loggerFor(ctx)
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		route := routeTemplate(r, r.URL.Path)
+
+		event := logger.Info()
+		if quietRoutes[route] {
+			event = logger.Debug()
+		}
+
+		event.
+			Str("method", r.Method).
+			Str("route", route).
+			Str("path", r.URL.Path).
+			Int("status", recorder.status).
+			Dur("duration", time.Since(start)).
+			Str("remoteAddr", r.RemoteAddr).
+			Str("userAgent", r.UserAgent()).
+			Msg("handled request")
+	})
+}
+
+// requestLogUnaryInterceptor is the gRPC counterpart of requestLogHandler,
+// reading and returning the request ID in the x-request-id metadata.
+func (s *Service) requestLogUnaryInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	start := time.Now()
+
+	var requestID string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(requestIDHeader); len(values) > 0 {
+			requestID = values[0]
+		}
+	}
+
+	requestID = requestIDOrNew(requestID)
+	_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDHeader, requestID))
+
+	ctx = s.requestContext(ctx, requestID)
+
+	res, err := handler(ctx, req)
+
+	s.loggerFor(ctx).Info().
+		Str("method", info.FullMethod).
+		Str("code", status.Code(err).String()).
+		Dur("duration", time.Since(start)).
+		Msg("handled request")
+
+	return res, err
+}
+
+type requestIDKey struct{}
+
+// requestIDFromContext returns the ID of the request being handled in the context,
+// or an empty string outside of a request.
+func requestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// requestContext attaches the request ID and a logger for the request to the context.
+// The logger is tagged with the request ID and the trace ID if the request is traced.
+func (s *Service) requestContext(ctx context.Context, requestID string) context.Context {
+	ctx = context.WithValue(ctx, requestIDKey{}, requestID)
+
+	return logging.With(ctx, s.logger, func(c zerolog.Context) zerolog.Context {
+		c = c.Str("requestId", requestID)
+
+		if traceID := tracing.TraceID(ctx); traceID != "" {
+			c = c.Str("traceId", traceID)
+		}
+
+		return c
+	})
+}
+
+// requestIDOrNew returns the request ID chosen by the client, or a new random ID
+// if the client did not choose one or chose one that is unsafe to log.
+func requestIDOrNew(requestID string) string {
+	if isValidRequestID(requestID) {
+		return requestID
+	}
+
+	return newRandomID()
+}
+
+// newRandomID returns a random 128-bit hex-encoded ID.
+func newRandomID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+func isValidRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+
+	for _, c := range requestID {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// routeTemplate returns the template of the route matched by a request, or the fallback if none matched.
+func routeTemplate(r *http.Request, fallback string) string {
+	if current := mux.CurrentRoute(r); current != nil {
+		if template, err := current.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+
+	return fallback
+}
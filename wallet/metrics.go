@@ -1,14 +1,59 @@
 package wallet
 
 import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rs/zerolog"
+
+	"github.com/onflow/flow-account-api/model"
+	"github.com/onflow/flow-account-api/storage"
 )
 
 const metricsNamespace = "flow"
 
+// Classes of account creation failures, used to label the failure counter.
+const (
+	creationErrorLimitExceeded     = "limit_exceeded"
+	creationErrorInvalidRequest    = "invalid_request"
+	creationErrorAccessAPI         = "access_api"
+	creationErrorSigning           = "signing"
+	creationErrorTransactionFailed = "transaction_failed"
+	creationErrorConflict          = "conflict"
+	creationErrorStore             = "store"
+	creationErrorUnknown           = "unknown"
+)
+
+const (
+	resultOK       = "ok"
+	resultNotFound = "not_found"
+	resultError    = "error"
+)
+
+// AccountsCollector records metrics for the service.
+//
+// It registers its metrics with the default Prometheus registry, so only one
+// collector can be created per process.
 type AccountsCollector struct {
 	accounts prometheus.Gauge
+
+	httpRequests        *prometheus.CounterVec
+	httpRequestDuration *prometheus.HistogramVec
+
+	creationAttempts  prometheus.Counter
+	creationSuccesses prometheus.Counter
+	creationFailures  *prometheus.CounterVec
+
+	transactionSealDuration prometheus.Histogram
+	accessAPIDuration       *prometheus.HistogramVec
+	storeQueryDuration      *prometheus.HistogramVec
+
+	webhookDeliveries *prometheus.CounterVec
 }
 
 func NewAccountsCollector(networkType string) *AccountsCollector {
@@ -21,6 +66,74 @@ func NewAccountsCollector(networkType string) *AccountsCollector {
 			Subsystem: networkType,
 			Help:      "the number of accounts created by the service",
 		}),
+
+		httpRequests: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name:      "http_requests_total",
+			Namespace: metricsNamespace,
+			Subsystem: networkType,
+			Help:      "the number of HTTP requests handled, by route, method and status code",
+		}, []string{"route", "method", "code"}),
+
+		httpRequestDuration: promauto.NewHistogramVec(prometheus.HistogramOpts{
+			Name:      "http_request_duration_seconds",
+			Namespace: metricsNamespace,
+			Subsystem: networkType,
+			Help:      "the time taken to handle HTTP requests, by route and method",
+			// account creation waits for a transaction to be sealed, so allow for long requests
+			Buckets: []float64{0.005, 0.01, 0.05, 0.1, 0.5, 1, 2.5, 5, 10, 30, 60},
+		}, []string{"route", "method"}),
+
+		creationAttempts: promauto.NewCounter(prometheus.CounterOpts{
+			Name:      "account_creation_attempts_total",
+			Namespace: metricsNamespace,
+			Subsystem: networkType,
+			Help:      "the number of account creation requests",
+		}),
+
+		creationSuccesses: promauto.NewCounter(prometheus.CounterOpts{
+			Name:      "account_creation_successes_total",
+			Namespace: metricsNamespace,
+			Subsystem: networkType,
+			Help:      "the number of accounts created and stored",
+		}),
+
+		creationFailures: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name:      "account_creation_failures_total",
+			Namespace: metricsNamespace,
+			Subsystem: networkType,
+			Help:      "the number of failed account creation requests, by error class",
+		}, []string{"class"}),
+
+		transactionSealDuration: promauto.NewHistogram(prometheus.HistogramOpts{
+			Name:      "transaction_seal_duration_seconds",
+			Namespace: metricsNamespace,
+			Subsystem: networkType,
+			Help:      "the time from submitting an account creation transaction to it being sealed",
+			Buckets:   []float64{1, 2, 5, 10, 15, 20, 30, 45, 60, 90, 120},
+		}),
+
+		accessAPIDuration: promauto.NewHistogramVec(prometheus.HistogramOpts{
+			Name:      "access_api_request_duration_seconds",
+			Namespace: metricsNamespace,
+			Subsystem: networkType,
+			Help:      "the latency of access API calls, by method and result",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "result"}),
+
+		storeQueryDuration: promauto.NewHistogramVec(prometheus.HistogramOpts{
+			Name:      "store_query_duration_seconds",
+			Namespace: metricsNamespace,
+			Subsystem: networkType,
+			Help:      "the latency of store operations, by operation and result",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"operation", "result"}),
+
+		webhookDeliveries: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name:      "webhook_delivery_attempts_total",
+			Namespace: metricsNamespace,
+			Subsystem: networkType,
+			Help:      "the number of attempts to send webhook callbacks, by subscription and outcome",
+		}, []string{"subscription", "outcome"}),
 	}
 
 	return ac
@@ -30,3 +143,294 @@ func NewAccountsCollector(networkType string) *AccountsCollector {
 func (ac *AccountsCollector) CurrentNumberOfAccounts(accounts int) {
 	ac.accounts.Set(float64(accounts))
 }
+
+func (ac *AccountsCollector) accountCreationAttempted() {
+	ac.creationAttempts.Inc()
+}
+
+func (ac *AccountsCollector) accountCreated() {
+	ac.creationSuccesses.Inc()
+}
+
+func (ac *AccountsCollector) accountCreationFailed(class string) {
+	ac.creationFailures.WithLabelValues(class).Inc()
+}
+
+func (ac *AccountsCollector) transactionSealed(duration time.Duration) {
+	ac.transactionSealDuration.Observe(duration.Seconds())
+}
+
+func (ac *AccountsCollector) accessAPIRequest(method string, start time.Time, err error) {
+	ac.accessAPIDuration.WithLabelValues(method, resultLabel(err)).Observe(time.Since(start).Seconds())
+}
+
+func (ac *AccountsCollector) storeQuery(operation string, start time.Time, err error) {
+	ac.storeQueryDuration.WithLabelValues(operation, resultLabel(err)).Observe(time.Since(start).Seconds())
+}
+
+func (ac *AccountsCollector) webhookDeliveryAttempted(subscription, outcome string) {
+	ac.webhookDeliveries.WithLabelValues(subscription, outcome).Inc()
+}
+
+func resultLabel(err error) string {
+	switch {
+	case err == nil:
+		return resultOK
+	case errors.Is(err, storage.ErrNotFound):
+		return resultNotFound
+	default:
+		return resultError
+	}
+}
+
+// creationError classifies a failure to create an account on chain.
+type creationError struct {
+	class string
+	err   error
+	// transactionID is set if the creation transaction may have been submitted.
+	transactionID string
+}
+
+func (e *creationError) Error() string {
+	return e.err.Error()
+}
+
+func (e *creationError) Unwrap() error {
+	return e.err
+}
+
+func creationErrorClass(err error) string {
+	var creationErr *creationError
+	if errors.As(err, &creationErr) {
+		return creationErr.class
+	}
+
+	return creationErrorUnknown
+}
+
+// instrumentHandler records the status and duration of each request by route template,
+// so that requests for different accounts are counted under the same route.
+func (ac *AccountsCollector) instrumentHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := routeTemplate(r, "unknown")
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		start := time.Now()
+		next.ServeHTTP(recorder, r)
+
+		ac.httpRequestDuration.WithLabelValues(route, r.Method).Observe(time.Since(start).Seconds())
+		ac.httpRequests.WithLabelValues(route, r.Method, strconv.Itoa(recorder.status)).Inc()
+	})
+}
+
+// statusRecorder captures the status code written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// instrumentedStore records the latency of every store operation.
+// The service and the routines that query the store in the background all wrap it,
+// so the latency metrics cover every query the process makes.
+//
+// Queries are traced by the Postgres store itself, where the SQL they run is known.
+type instrumentedStore struct {
+	storage.Store
+	metrics *AccountsCollector
+}
+
+func newInstrumentedStore(store storage.Store, metrics *AccountsCollector) storage.Store {
+	return &instrumentedStore{Store: store, metrics: metrics}
+}
+
+// observe starts timing a store operation. The returned function records its latency.
+func (s *instrumentedStore) observe(operation string) func(err error) {
+	start := time.Now()
+
+	return func(err error) {
+		s.metrics.storeQuery(operation, start, err)
+	}
+}
+
+func (s *instrumentedStore) InsertAccount(ctx context.Context, account *model.Account, event *model.AuditEvent) (err error) {
+	done := s.observe("InsertAccount")
+	defer func() { done(err) }()
+
+	return s.Store.InsertAccount(ctx, account, event)
+}
+
+func (s *instrumentedStore) GetAccountByPublicKey(ctx context.Context, publicKey string, account *model.Account) (err error) {
+	done := s.observe("GetAccountByPublicKey")
+	defer func() { done(err) }()
+
+	return s.Store.GetAccountByPublicKey(ctx, publicKey, account)
+}
+
+func (s *instrumentedStore) GetAccountsByPublicKey(ctx context.Context, publicKey string) (accounts []*model.Account, err error) {
+	done := s.observe("GetAccountsByPublicKey")
+	defer func() { done(err) }()
+
+	return s.Store.GetAccountsByPublicKey(ctx, publicKey)
+}
+
+func (s *instrumentedStore) GetAccountsByPublicKeys(ctx context.Context, publicKeys []string) (accounts map[string][]*model.Account, err error) {
+	done := s.observe("GetAccountsByPublicKeys")
+	defer func() { done(err) }()
+
+	return s.Store.GetAccountsByPublicKeys(ctx, publicKeys)
+}
+
+func (s *instrumentedStore) GetAccountByAddress(ctx context.Context, address string, account *model.Account) (err error) {
+	done := s.observe("GetAccountByAddress")
+	defer func() { done(err) }()
+
+	return s.Store.GetAccountByAddress(ctx, address, account)
+}
+
+func (s *instrumentedStore) GetAccountCount(ctx context.Context) (count int, err error) {
+	done := s.observe("GetAccountCount")
+	defer func() { done(err) }()
+
+	return s.Store.GetAccountCount(ctx)
+}
+
+func (s *instrumentedStore) ListAccounts(
+	ctx context.Context,
+	filter storage.AccountFilter,
+	cursor storage.Cursor,
+	limit int,
+) (page *storage.AccountPage, err error) {
+	done := s.observe("ListAccounts")
+	defer func() { done(err) }()
+
+	return s.Store.ListAccounts(ctx, filter, cursor, limit)
+}
+
+func (s *instrumentedStore) Ping(ctx context.Context) (err error) {
+	done := s.observe("Ping")
+	defer func() { done(err) }()
+
+	return s.Store.Ping(ctx)
+}
+
+func (s *instrumentedStore) InsertAuditEvent(ctx context.Context, event *model.AuditEvent) (err error) {
+	done := s.observe("InsertAuditEvent")
+	defer func() { done(err) }()
+
+	return s.Store.InsertAuditEvent(ctx, event)
+}
+
+func (s *instrumentedStore) ListAuditEvents(
+	ctx context.Context,
+	filter storage.AuditEventFilter,
+	afterID int64,
+	limit int,
+) (page *storage.AuditEventPage, err error) {
+	done := s.observe("ListAuditEvents")
+	defer func() { done(err) }()
+
+	return s.Store.ListAuditEvents(ctx, filter, afterID, limit)
+}
+
+func (s *instrumentedStore) InsertWebhookDeliveries(ctx context.Context, deliveries []*model.WebhookDelivery) (err error) {
+	done := s.observe("InsertWebhookDeliveries")
+	defer func() { done(err) }()
+
+	return s.Store.InsertWebhookDeliveries(ctx, deliveries)
+}
+
+func (s *instrumentedStore) ClaimWebhookDeliveries(
+	ctx context.Context,
+	now time.Time,
+	lease time.Duration,
+	limit int,
+) (deliveries []*model.WebhookDelivery, err error) {
+	done := s.observe("ClaimWebhookDeliveries")
+	defer func() { done(err) }()
+
+	return s.Store.ClaimWebhookDeliveries(ctx, now, lease, limit)
+}
+
+func (s *instrumentedStore) UpdateWebhookDelivery(ctx context.Context, delivery *model.WebhookDelivery) (err error) {
+	done := s.observe("UpdateWebhookDelivery")
+	defer func() { done(err) }()
+
+	return s.Store.UpdateWebhookDelivery(ctx, delivery)
+}
+
+func (s *instrumentedStore) ListWebhookDeliveries(
+	ctx context.Context,
+	status string,
+	afterID int64,
+	limit int,
+) (page *storage.WebhookDeliveryPage, err error) {
+	done := s.observe("ListWebhookDeliveries")
+	defer func() { done(err) }()
+
+	return s.Store.ListWebhookDeliveries(ctx, status, afterID, limit)
+}
+
+// AccountCountRefresher updates the account count gauge on a fixed interval,
+// whether or not an account limit is configured.
+type AccountCountRefresher struct {
+	store    storage.Store
+	metrics  *AccountsCollector
+	interval time.Duration
+	logger   zerolog.Logger
+	done     chan bool
+}
+
+func NewAccountCountRefresher(
+	store storage.Store,
+	metrics *AccountsCollector,
+	interval time.Duration,
+	logger zerolog.Logger,
+) *AccountCountRefresher {
+	return &AccountCountRefresher{
+		store:    newInstrumentedStore(store, metrics),
+		metrics:  metrics,
+		interval: interval,
+		logger:   logger,
+		done:     make(chan bool, 1),
+	}
+}
+
+// Start refreshes the account count until the refresher is stopped.
+func (r *AccountCountRefresher) Start() error {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	r.refresh()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.refresh()
+		case <-r.done:
+			return nil
+		}
+	}
+}
+
+func (r *AccountCountRefresher) Stop() {
+	r.done <- true
+}
+
+func (r *AccountCountRefresher) refresh() {
+	ctx, cancel := context.WithTimeout(context.Background(), r.interval)
+	defer cancel()
+
+	count, err := r.store.GetAccountCount(ctx)
+	if err != nil {
+		r.logger.Warn().Err(err).Msg("failed to refresh account count")
+		return
+	}
+
+	r.metrics.CurrentNumberOfAccounts(count)
+}
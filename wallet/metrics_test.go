@@ -0,0 +1,28 @@
+package wallet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/onflow/flow-account-api/storage"
+	"github.com/onflow/flow-account-api/storage/memory"
+)
+
+// TestStoreQueriesAreInstrumented checks that every component querying the store records the latency of its queries.
+func TestStoreQueriesAreInstrumented(t *testing.T) {
+	store := memory.NewStore()
+
+	stores := map[string]storage.Store{
+		"Service":               newTestService(t, 0).store,
+		"AccountCountRefresher": NewAccountCountRefresher(store, testMetrics, time.Minute, zerolog.Nop()).store,
+		"WebhookDispatcher":     NewWebhookDispatcher(store, WebhookConfig{}, testMetrics, zerolog.Nop()).store,
+	}
+
+	for name, s := range stores {
+		if _, ok := s.(*instrumentedStore); !ok {
+			t.Errorf("%s queries the store without recording their latency", name)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package wallet
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/onflow/flow-go-sdk/crypto"
+)
+
+// keyAlgorithms is a signature and hash algorithm pair that an account key may use.
+type keyAlgorithms struct {
+	sigAlgo  crypto.SignatureAlgorithm
+	hashAlgo crypto.HashAlgorithm
+}
+
+// accountKeyAlgorithms are the algorithm pairs Flow accepts for account keys.
+// BLS keys and the 384-bit hash algorithms can only be used by nodes.
+var accountKeyAlgorithms = []keyAlgorithms{
+	{crypto.ECDSA_P256, crypto.SHA2_256},
+	{crypto.ECDSA_P256, crypto.SHA3_256},
+	{crypto.ECDSA_secp256k1, crypto.SHA2_256},
+	{crypto.ECDSA_secp256k1, crypto.SHA3_256},
+}
+
+// keyPolicies lists the algorithm pairs accepted for new account keys on each network.
+// Every network accepts all of accountKeyAlgorithms for now; an entry can be narrowed
+// without affecting the other networks.
+var keyPolicies = map[string][]keyAlgorithms{
+	"emulator": accountKeyAlgorithms,
+	"testnet":  accountKeyAlgorithms,
+	"mainnet":  accountKeyAlgorithms,
+}
+
+// allowedKeyAlgorithms returns the algorithm pairs accepted for new account keys on a network.
+// Networks without a policy accept accountKeyAlgorithms.
+func allowedKeyAlgorithms(network string) []keyAlgorithms {
+	allowed, ok := keyPolicies[network]
+	if !ok {
+		return accountKeyAlgorithms
+	}
+
+	return allowed
+}
+
+// accountKeySigAlgos returns the signature algorithms of accountKeyAlgorithms, in order.
+func accountKeySigAlgos() []crypto.SignatureAlgorithm {
+	var sigAlgos []crypto.SignatureAlgorithm
+
+	for _, pair := range accountKeyAlgorithms {
+		if len(sigAlgos) == 0 || sigAlgos[len(sigAlgos)-1] != pair.sigAlgo {
+			sigAlgos = append(sigAlgos, pair.sigAlgo)
+		}
+	}
+
+	return sigAlgos
+}
+
+// accountKeyAlgorithmsFor returns the algorithms with the given names if the policy of the network
+// allows them to be used together by an account key, checking them before anything is sent to chain.
+//
+// The returned error wraps errInvalidSigAlgo or errInvalidHashAlgo and lists the allowed alternatives.
+func accountKeyAlgorithmsFor(network, sigAlgoName, hashAlgoName string) (crypto.SignatureAlgorithm, crypto.HashAlgorithm, error) {
+	var (
+		sigAlgos  []string
+		hashAlgos []string
+		sigAlgo   = crypto.UnknownSignatureAlgorithm
+	)
+
+	for _, pair := range allowedKeyAlgorithms(network) {
+		if !containsString(sigAlgos, pair.sigAlgo.String()) {
+			sigAlgos = append(sigAlgos, pair.sigAlgo.String())
+		}
+
+		if pair.sigAlgo.String() != sigAlgoName {
+			continue
+		}
+
+		sigAlgo = pair.sigAlgo
+
+		if pair.hashAlgo.String() == hashAlgoName {
+			return pair.sigAlgo, pair.hashAlgo, nil
+		}
+
+		hashAlgos = append(hashAlgos, pair.hashAlgo.String())
+	}
+
+	if sigAlgo == crypto.UnknownSignatureAlgorithm {
+		return sigAlgo, crypto.UnknownHashAlgorithm, fmt.Errorf(
+			"%w: %q is not supported for account keys, must be one of %s",
+			errInvalidSigAlgo,
+			sigAlgoName,
+			strings.Join(sigAlgos, ", "),
+		)
+	}
+
+	return sigAlgo, crypto.UnknownHashAlgorithm, fmt.Errorf(
+		"%w: %q is not supported for %s account keys, must be one of %s",
+		errInvalidHashAlgo,
+		hashAlgoName,
+		sigAlgo,
+		strings.Join(hashAlgos, ", "),
+	)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,242 @@
+package wallet
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+)
+
+// ServerConfig configures the HTTP servers of the service.
+type ServerConfig struct {
+	Port int
+
+	// InternalAddress is the address of the internal server.
+	InternalAddress string
+
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+
+	// MaxBodyBytes bounds the size of request bodies. If zero, bodies are not bounded.
+	MaxBodyBytes int64
+
+	// TLSCertPath and TLSKeyPath are the PEM files of the certificate served by the public API.
+	// If empty, the API is served over plain HTTP.
+	TLSCertPath string
+	TLSKeyPath  string
+}
+
+func (c ServerConfig) tlsEnabled() bool {
+	return c.TLSCertPath != "" || c.TLSKeyPath != ""
+}
+
+// newServer creates an HTTP server with the configured timeouts.
+func (c ServerConfig) newServer(addr string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadTimeout:       c.ReadTimeout,
+		ReadHeaderTimeout: c.ReadHeaderTimeout,
+		WriteTimeout:      c.WriteTimeout,
+		IdleTimeout:       c.IdleTimeout,
+	}
+}
+
+// errRequestBodyTooLarge is returned when reading a request body beyond the configured limit.
+var errRequestBodyTooLarge = errors.New("request body too large")
+
+// limitBodyHandler bounds the size of request bodies, rejecting requests that declare a larger body up front.
+func limitBodyHandler(maxBytes int64) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		if maxBytes <= 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > maxBytes {
+				respondWithError(w, http.StatusRequestEntityTooLarge, errRequestBodyTooLarge.Error())
+				return
+			}
+
+			r.Body = &limitedBody{
+				ReadCloser: http.MaxBytesReader(w, r.Body, maxBytes),
+				remaining:  maxBytes,
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// limitedBody replaces the error of a body cut off by http.MaxBytesReader with errRequestBodyTooLarge.
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+
+	if err != nil && err != io.EOF && b.remaining <= 0 {
+		return n, errRequestBodyTooLarge
+	}
+
+	return n, err
+}
+
+// errTrailingData is returned when a request body holds more than one JSON value.
+var errTrailingData = errors.New("request body must contain a single JSON value")
+
+// decodeJSONBody decodes a request body holding a single JSON value,
+// rejecting fields that are not part of the request type.
+func decodeJSONBody(r *http.Request, v interface{}) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(v)
+	if err != nil {
+		return err
+	}
+
+	if _, err := decoder.Token(); err != io.EOF {
+		if errors.Is(err, errRequestBodyTooLarge) {
+			return err
+		}
+
+		return errTrailingData
+	}
+
+	return nil
+}
+
+// respondWithDecodeError responds to a request whose body could not be decoded by decodeJSONBody.
+func respondWithDecodeError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errRequestBodyTooLarge) {
+		respondWithError(w, http.StatusRequestEntityTooLarge, err.Error())
+		return
+	}
+
+	if errors.Is(err, errTrailingData) {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("invalid request payload: %s", err))
+		return
+	}
+
+	// encoding/json has no error type for unknown fields
+	if strings.HasPrefix(err.Error(), "json: unknown field ") {
+		respondWithError(
+			w,
+			http.StatusBadRequest,
+			fmt.Sprintf("invalid request payload: %s", strings.TrimPrefix(err.Error(), "json: ")),
+		)
+		return
+	}
+
+	respondWithError(w, http.StatusBadRequest, "invalid request payload")
+}
+
+// certReloadInterval is how often the certificate files are checked for changes.
+const certReloadInterval = 10 * time.Second
+
+// certReloader serves a TLS certificate from files, reloading it when the files change
+// so that renewed certificates are served without a restart.
+type certReloader struct {
+	certPath  string
+	keyPath   string
+	logger    zerolog.Logger
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+func newCertReloader(certPath, keyPath string, logger zerolog.Logger) (*certReloader, error) {
+	c := &certReloader{
+		certPath:  certPath,
+		keyPath:   keyPath,
+		logger:    logger,
+		checkedAt: time.Now(),
+	}
+
+	modTime, err := c.filesModTime()
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.load(modTime)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// getCertificate returns the current certificate, reloading it first if the files have changed.
+//
+// If the changed files cannot be loaded, for example while they are being replaced,
+// the previous certificate is served until the next check.
+func (c *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Since(c.checkedAt) < certReloadInterval {
+		return c.cert, nil
+	}
+
+	c.checkedAt = time.Now()
+
+	modTime, err := c.filesModTime()
+	if err == nil && !modTime.Equal(c.modTime) {
+		err = c.load(modTime)
+		if err == nil {
+			c.logger.Info().Msg("reloaded TLS certificate")
+		}
+	}
+
+	if err != nil {
+		c.logger.Warn().Err(err).Msg("failed to reload TLS certificate, serving the previous certificate")
+	}
+
+	return c.cert, nil
+}
+
+func (c *certReloader) load(modTime time.Time) error {
+	cert, err := tls.LoadX509KeyPair(c.certPath, c.keyPath)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+
+	c.cert = &cert
+	c.modTime = modTime
+
+	return nil
+}
+
+// filesModTime returns the latest modification time of the certificate and key files.
+func (c *certReloader) filesModTime() (time.Time, error) {
+	var latest time.Time
+
+	for _, path := range []string{c.certPath, c.keyPath} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed to read TLS certificate: %w", err)
+		}
+
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+
+	return latest, nil
+}
@@ -2,54 +2,95 @@ package wallet
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/cors"
 	"github.com/rs/zerolog"
 
-	"github.com/onflow/flow-go-sdk"
-	"github.com/onflow/flow-go-sdk/crypto"
 	"github.com/onflow/flow-account-api/model"
+	"github.com/onflow/flow-account-api/pkg/logging"
 	"github.com/onflow/flow-account-api/storage"
+	"github.com/onflow/flow-go-sdk"
 )
 
 // Service is a hardware wallet service.
 type Service struct {
-	httpServer *http.Server
-	logger     zerolog.Logger
-	accounts   *Accounts
-	store      storage.Store
-	metrics    *AccountsCollector
+	httpServer          *http.Server
+	conf                ServerConfig
+	logger              zerolog.Logger
+	accounts            *Accounts
+	store               storage.Store
+	metrics             *AccountsCollector
+	webhooks            []WebhookSubscription
+	creations           creationTracker
+	shutdownTimeout     time.Duration
+	singleAccountLookup bool
 }
 
-// NewService creates a new hardware wallet service.
-func NewService(port int, logger zerolog.Logger, accounts *Accounts, store storage.Store, networkType string) *Service {
+// NewService creates a new hardware wallet service serving the public account API.
+//
+// Health checks, metrics and admin routes are served by an InternalServer.
+//
+// If singleAccountLookup is true, GET /accounts responds with only the first account
+// associated with a public key, as it did before keys could control multiple accounts.
+//
+// On shutdown the service waits up to shutdownTimeout for requests and account creations in flight.
+func NewService(
+	conf ServerConfig,
+	logger zerolog.Logger,
+	accounts *Accounts,
+	store storage.Store,
+	metrics *AccountsCollector,
+	webhooks []WebhookSubscription,
+	shutdownTimeout time.Duration,
+	singleAccountLookup bool,
+) *Service {
 	s := &Service{
-		logger:   logger,
-		accounts: accounts,
-		store:    store,
-		metrics:  NewAccountsCollector(networkType),
+		conf:                conf,
+		logger:              logger,
+		accounts:            accounts,
+		store:               newInstrumentedStore(store, metrics),
+		metrics:             metrics,
+		webhooks:            webhooks,
+		shutdownTimeout:     shutdownTimeout,
+		singleAccountLookup: singleAccountLookup,
 	}
 
 	router := mux.NewRouter()
 
+	router.Use(
+		traceHandler,
+		s.requestLogHandler,
+		metrics.instrumentHandler,
+		limitBodyHandler(conf.MaxBodyBytes),
+	)
+
 	router.
-		Handle("/metrics", promhttp.Handler())
+		HandleFunc("/accounts", s.createAccount).
+		Methods(http.MethodPost)
 
 	router.
-		HandleFunc("/health", healthCheck)
+		HandleFunc("/accounts", s.getAccount).
+		Methods(http.MethodGet)
 
 	router.
-		HandleFunc("/accounts", s.createAccount).
+		HandleFunc("/accounts/lookup", s.lookupAccounts).
 		Methods(http.MethodPost)
 
+	// registered ahead of /accounts/{address}, which would otherwise reject "list" as an invalid address
 	router.
-		HandleFunc("/accounts", s.getAccount).
+		HandleFunc("/accounts/list", movedToInternalServer).
+		Methods(http.MethodGet)
+
+	router.
+		HandleFunc("/accounts/{address}", s.getAccountByAddress).
 		Methods(http.MethodGet)
 
 	// TODO: allow CORS options to be configured via environment variable
@@ -60,16 +101,33 @@ func NewService(port int, logger zerolog.Logger, accounts *Accounts, store stora
 
 	handler := c.Handler(router)
 
-	s.httpServer = &http.Server{
-		Addr:    fmt.Sprintf(":%d", port),
-		Handler: handler,
-	}
+	s.httpServer = conf.newServer(fmt.Sprintf(":%d", conf.Port), handler)
 
 	return s
 }
 
+// Start serves the API until the service is stopped, over TLS if a certificate is configured.
 func (s *Service) Start() error {
-	err := s.httpServer.ListenAndServe()
+	var err error
+
+	if s.conf.tlsEnabled() {
+		var certs *certReloader
+
+		certs, err = newCertReloader(s.conf.TLSCertPath, s.conf.TLSKeyPath, s.logger)
+		if err != nil {
+			return err
+		}
+
+		s.httpServer.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: certs.getCertificate,
+		}
+
+		err = s.httpServer.ListenAndServeTLS("", "")
+	} else {
+		err = s.httpServer.ListenAndServe()
+	}
+
 	if errors.Is(err, http.ErrServerClosed) {
 		return nil
 	}
@@ -77,136 +135,405 @@ func (s *Service) Start() error {
 	return err
 }
 
+// Stop stops accepting account creations and waits for the requests and creations
+// in flight to finish, for up to the shutdown timeout.
+//
+// Creations requested over gRPC are waited for too, so the store must be closed
+// only once Stop has returned.
 func (s *Service) Stop() {
-	_ = s.httpServer.Shutdown(context.Background())
+	ctx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+
+	// refuse new creations before closing the listener, so that none begin after the drain
+	inFlight, err := s.creations.drain(ctx)
+	if err != nil {
+		s.logger.Error().
+			Err(err).
+			Int("inFlight", inFlight).
+			Msg("shutdown timed out with account creations in flight, their accounts may be missing from the store")
+	}
+
+	err = s.httpServer.Shutdown(ctx)
+	if err != nil {
+		s.logger.Warn().Err(err).Msg("failed to shut down HTTP server gracefully")
+	}
 }
 
+// clientIDHeader identifies the API client on whose behalf an account is created.
+const clientIDHeader = "X-Client-ID"
+
 type createAccountRequest struct {
 	PublicKey string `json:"publicKey"`
 	SigAlgo   string `json:"signatureAlgorithm"`
 	HashAlgo  string `json:"hashAlgorithm"`
 }
 
-func (s *Service) createAccount(w http.ResponseWriter, r *http.Request) {
-	// Double check that we haven't exceeded our limit
-	if s.exceededAccountLimit() {
-		respondWithError(w, http.StatusForbidden, "service out of available accounts")
-		return
-	}
+var (
+	errInvalidSigAlgo   = errors.New("invalid signature algorithm")
+	errInvalidHashAlgo  = errors.New("invalid hash algorithm")
+	errInvalidPublicKey = errors.New("invalid public key")
+)
 
+func (s *Service) createAccount(w http.ResponseWriter, r *http.Request) {
 	var req createAccountRequest
 
-	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(&req); err != nil {
-		respondWithError(w, http.StatusBadRequest, "invalid request payload")
+	if err := decodeJSONBody(r, &req); err != nil {
+		s.metrics.accountCreationAttempted()
+		s.metrics.accountCreationFailed(creationErrorInvalidRequest)
+
+		respondWithDecodeError(w, err)
 		return
 	}
 
-	sigAlgo := crypto.StringToSignatureAlgorithm(req.SigAlgo)
-	if sigAlgo == crypto.UnknownSignatureAlgorithm {
-		respondWithError(w, http.StatusBadRequest, "invalid signature algorithm")
+	account, err := s.CreateAccount(r.Context(), req.PublicKey, req.SigAlgo, req.HashAlgo, clientMetadataFromRequest(r))
+	if err != nil {
+		if errors.Is(err, ErrAccountLimitExceeded) {
+			respondWithError(w, http.StatusForbidden, err.Error())
+			return
+		}
+
+		if errors.Is(err, ErrShuttingDown) {
+			respondWithError(w, http.StatusServiceUnavailable, err.Error())
+			return
+		}
+
+		if isInvalidAccountKey(err) {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if errors.Is(err, storage.ErrExists) {
+			respondWithError(
+				w,
+				http.StatusConflict,
+				"account with address or public key already exists",
+			)
+			return
+		}
+
+		respondWithError(
+			w,
+			http.StatusInternalServerError,
+			"failed to create account",
+		)
 		return
 	}
 
-	hashAlgo := crypto.StringToHashAlgorithm(req.HashAlgo)
-	if hashAlgo == crypto.UnknownHashAlgorithm {
-		respondWithError(w, http.StatusBadRequest, "invalid hash algorithm")
-		return
+	respondWithJSON(w, http.StatusCreated, &account)
+}
+
+// newAccountKey decodes a public key and its algorithms into an account key
+// with full signing weight, if the key policy of the network allows the algorithms.
+func newAccountKey(network, encodedPublicKey, sigAlgoName, hashAlgoName string) (*flow.AccountKey, error) {
+	sigAlgo, hashAlgo, err := accountKeyAlgorithmsFor(network, sigAlgoName, hashAlgoName)
+	if err != nil {
+		return nil, err
 	}
 
-	publicKey, err := crypto.DecodePublicKeyHex(sigAlgo, req.PublicKey)
+	publicKey, err := decodePublicKey(sigAlgo, encodedPublicKey)
 	if err != nil {
-		fmt.Println(err)
-		respondWithError(w, http.StatusBadRequest, "invalid public key")
-		return
+		return nil, err
 	}
 
-	accountKey := flow.NewAccountKey().
+	return flow.NewAccountKey().
 		SetPublicKey(publicKey).
 		SetHashAlgo(hashAlgo).
-		SetWeight(flow.AccountKeyWeightThreshold)
+		SetWeight(flow.AccountKeyWeightThreshold), nil
+}
+
+// isInvalidAccountKey reports whether an error was returned by newAccountKey.
+func isInvalidAccountKey(err error) bool {
+	return errors.Is(err, errInvalidSigAlgo) ||
+		errors.Is(err, errInvalidHashAlgo) ||
+		errors.Is(err, errInvalidPublicKey)
+}
+
+// ErrAccountLimitExceeded is returned by CreateAccount when the configured account limit has been reached.
+var ErrAccountLimitExceeded = errors.New("service out of available accounts")
+
+// CreateAccount creates and stores an account controlled by the given public key.
+// It backs both the HTTP and gRPC create account endpoints.
+func (s *Service) CreateAccount(
+	ctx context.Context,
+	encodedPublicKey, sigAlgoName, hashAlgoName string,
+	client model.ClientMetadata,
+) (*model.Account, error) {
+	if !s.creations.begin() {
+		return nil, ErrShuttingDown
+	}
+	defer s.creations.end()
+
+	s.metrics.accountCreationAttempted()
+
+	// Double check that we haven't exceeded our limit
+	if s.exceededAccountLimit(ctx) {
+		s.metrics.accountCreationFailed(creationErrorLimitExceeded)
+		s.recordRejectedCreation(ctx, client, encodedPublicKey, ErrAccountLimitExceeded)
+		return nil, ErrAccountLimitExceeded
+	}
+
+	ctx = logging.With(ctx, s.logger, func(c zerolog.Context) zerolog.Context {
+		return c.Str("publicKey", encodedPublicKey)
+	})
+
+	accountKey, err := newAccountKey(s.accounts.network, encodedPublicKey, sigAlgoName, hashAlgoName)
+	if err != nil {
+		s.metrics.accountCreationFailed(creationErrorInvalidRequest)
+		s.loggerFor(ctx).Info().Err(err).Msg("rejected invalid account key")
+		s.recordRejectedCreation(ctx, client, encodedPublicKey, err)
+		return nil, err
+	}
+
+	account, err := s.createAndStoreAccount(ctx, accountKey, client)
+	if err != nil {
+		return nil, err
+	}
+
+	s.metrics.accountCreated()
+
+	return account, nil
+}
+
+// createAndStoreAccount creates a new account on chain and records it in the store.
+//
+// The returned error wraps storage.ErrExists if the account or its key is already registered.
+func (s *Service) createAndStoreAccount(ctx context.Context, accountKey *flow.AccountKey, client model.ClientMetadata) (*model.Account, error) {
+	logger := s.loggerFor(ctx)
+	publicKey := encodePublicKey(accountKey.PublicKey)
+
+	// once the transaction is sent the account will exist on chain,
+	// so see creation through even if the client has gone away
+	ctx = detachedContext{ctx}
+
+	account, err := s.accounts.Create(ctx, accountKey, client)
+	if err != nil {
+		s.metrics.accountCreationFailed(creationErrorClass(err))
+		logger.Error().Err(err).Msg("failed to create account")
+		s.recordFailedCreation(ctx, client, publicKey, nil, err)
+		return nil, err
+	}
+
+	ctx = logging.With(ctx, s.logger, func(c zerolog.Context) zerolog.Context {
+		return c.
+			Str("transactionId", account.CreationTransactionID).
+			Str("address", account.Address)
+	})
+
+	logger = s.loggerFor(ctx)
+
+	event := newCreationEvent(ctx, client, publicKey, account, nil)
+
+	err = s.store.InsertAccount(ctx, account, event)
+	if err != nil {
+		s.recordFailedCreation(ctx, client, publicKey, account, err)
 
-	account, err := s.accounts.Create(accountKey)
+		if errors.Is(err, storage.ErrExists) {
+			s.metrics.accountCreationFailed(creationErrorConflict)
+			logger.Error().Err(err).Msg("account with address or public key already exists")
+			return nil, err
+		}
+
+		s.metrics.accountCreationFailed(creationErrorStore)
+		logger.Error().Err(err).Msg("failed to store account")
+		return nil, err
+	}
+
+	s.queueWebhooks(ctx, event)
+
+	logger.Info().Msg("created account")
+
+	return account, nil
+}
+
+// detachedContext carries the values of its parent context
+// but is never cancelled and has no deadline.
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+func clientMetadataFromRequest(r *http.Request) model.ClientMetadata {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
 	if err != nil {
-		s.logger.Error().Err(err).Msg("failed to create account")
+		ip = r.RemoteAddr
+	}
+
+	return model.ClientMetadata{
+		ID:        r.Header.Get(clientIDHeader),
+		IP:        ip,
+		UserAgent: r.UserAgent(),
+	}
+}
 
+func (s *Service) getAccount(w http.ResponseWriter, r *http.Request) {
+	publicKeys, ok := r.URL.Query()["publicKey"]
+	if !ok {
 		respondWithError(
 			w,
-			http.StatusInternalServerError,
-			"failed to create account",
+			http.StatusBadRequest,
+			"publicKey is required",
+		)
+		return
+	}
+
+	if len(publicKeys) != 1 {
+		respondWithError(
+			w,
+			http.StatusBadRequest,
+			"must provide one public key",
 		)
 		return
 	}
 
-	err = s.store.InsertAccount(account)
+	publicKey, err := NormalizePublicKey(publicKeys[0])
 	if err != nil {
-		if errors.Is(err, storage.ErrExists) {
-			s.logger.Error().Err(err).Msg("account with address or public key already exists")
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	accounts, err := s.store.GetAccountsByPublicKey(r.Context(), publicKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			s.loggerFor(r.Context()).Error().Err(err).Msgf("account with public key %s does not exist", publicKey)
 
 			respondWithError(
 				w,
-				http.StatusConflict,
-				"account with address or public key already exists",
+				http.StatusNotFound,
+				fmt.Sprintf("account with public key %s does not exist", publicKey),
 			)
 			return
 		}
 
-		s.logger.Error().Err(err).Msg("failed to store account")
+		s.loggerFor(r.Context()).Error().Err(err).Msg("failed to get account by public key")
 
 		respondWithError(
 			w,
 			http.StatusInternalServerError,
-			"failed to create account",
+			"failed to get account by public key",
 		)
 		return
 	}
 
-	respondWithJSON(w, http.StatusCreated, &account)
+	if s.singleAccountLookup {
+		respondWithJSON(w, http.StatusOK, accounts[0])
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, accounts)
 }
 
-func (s *Service) getAccount(w http.ResponseWriter, r *http.Request) {
-	publicKeys, ok := r.URL.Query()["publicKey"]
-	if !ok {
+// maxLookupPublicKeys is the maximum number of public keys accepted by a single lookup request.
+const maxLookupPublicKeys = 100
+
+type lookupAccountsRequest struct {
+	PublicKeys []string `json:"publicKeys"`
+}
+
+type lookupAccountsResponse struct {
+	Accounts map[string][]*model.Account `json:"accounts"`
+}
+
+func (s *Service) lookupAccounts(w http.ResponseWriter, r *http.Request) {
+	var req lookupAccountsRequest
+
+	if err := decodeJSONBody(r, &req); err != nil {
+		respondWithDecodeError(w, err)
+		return
+	}
+
+	if len(req.PublicKeys) == 0 {
+		respondWithError(w, http.StatusBadRequest, "publicKeys is required")
+		return
+	}
+
+	if len(req.PublicKeys) > maxLookupPublicKeys {
 		respondWithError(
 			w,
 			http.StatusBadRequest,
-			"publicKey is required",
+			fmt.Sprintf("must provide at most %d public keys", maxLookupPublicKeys),
 		)
 		return
 	}
 
-	if len(publicKeys) != 1 {
+	// results are keyed by the public keys as the client sent them
+	normalizedPublicKeys := make(map[string]string, len(req.PublicKeys))
+
+	for _, publicKey := range req.PublicKeys {
+		normalizedPublicKey, err := NormalizePublicKey(publicKey)
+		if err != nil {
+			respondWithError(
+				w,
+				http.StatusBadRequest,
+				fmt.Sprintf("%s (%s)", err, publicKey),
+			)
+			return
+		}
+
+		normalizedPublicKeys[publicKey] = normalizedPublicKey
+	}
+
+	publicKeys := make([]string, 0, len(normalizedPublicKeys))
+	for _, normalizedPublicKey := range normalizedPublicKeys {
+		publicKeys = append(publicKeys, normalizedPublicKey)
+	}
+
+	matches, err := s.store.GetAccountsByPublicKeys(r.Context(), publicKeys)
+	if err != nil {
+		s.loggerFor(r.Context()).Error().Err(err).Msg("failed to get accounts by public keys")
+
 		respondWithError(
 			w,
-			http.StatusBadRequest,
-			"must provide one public key",
+			http.StatusInternalServerError,
+			"failed to get accounts by public keys",
 		)
 		return
 	}
 
-	publicKey := publicKeys[0]
+	accounts := make(map[string][]*model.Account)
+
+	for publicKey, normalizedPublicKey := range normalizedPublicKeys {
+		if match, ok := matches[normalizedPublicKey]; ok {
+			accounts[publicKey] = match
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, &lookupAccountsResponse{Accounts: accounts})
+}
+
+func (s *Service) getAccountByAddress(w http.ResponseWriter, r *http.Request) {
+	address := mux.Vars(r)["address"]
 
 	var account model.Account
 
-	err := s.store.GetAccountByPublicKey(publicKey, &account)
+	err := s.store.GetAccountByAddress(r.Context(), address, &account)
 	if err != nil {
-		if errors.Is(err, storage.ErrNotFound) {
-			s.logger.Error().Err(err).Msgf("account with public key %s does not exist", publicKey)
+		if errors.Is(err, storage.ErrInvalidAddress) {
+			respondWithError(
+				w,
+				http.StatusBadRequest,
+				fmt.Sprintf("invalid address %s", address),
+			)
+			return
+		}
 
+		if errors.Is(err, storage.ErrNotFound) {
 			respondWithError(
 				w,
 				http.StatusNotFound,
-				fmt.Sprintf("account with public key %s does not exist", publicKey),
+				fmt.Sprintf("account with address %s does not exist", address),
 			)
 			return
 		}
 
-		s.logger.Error().Err(err).Msg("failed to get account by public key")
+		s.loggerFor(r.Context()).Error().Err(err).Msg("failed to get account by address")
 
 		respondWithError(
 			w,
 			http.StatusInternalServerError,
-			"failed to get account by public key",
+			"failed to get account by address",
 		)
 		return
 	}
@@ -214,16 +541,16 @@ func (s *Service) getAccount(w http.ResponseWriter, r *http.Request) {
 	respondWithJSON(w, http.StatusOK, &account)
 }
 
-func (s *Service) exceededAccountLimit() bool {
+func (s *Service) exceededAccountLimit(ctx context.Context) bool {
 	maxAccounts := s.accounts.GetLimit()
 	if maxAccounts == 0 {
 		// Assume zero means infinite accounts
 		return false
 	}
 
-	numAccounts, err := s.store.GetAccountCount()
+	numAccounts, err := s.store.GetAccountCount(ctx)
 	if err != nil {
-		s.logger.Err(err).Msg("could not count number of accounts created by service")
+		s.loggerFor(ctx).Err(err).Msg("could not count number of accounts created by service")
 		// If we encounter an error, do not allow users to create any more accounts
 		return true
 	}
@@ -0,0 +1,90 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrShuttingDown is returned by CreateAccount once the service has begun to shut down.
+var ErrShuttingDown = errors.New("service is shutting down")
+
+// creationTracker tracks the account creations in flight so that shutdown can wait for them.
+//
+// An account exists on chain as soon as its transaction is sent, so a creation
+// cut short before its account is stored leaves an account the registry never learns about.
+type creationTracker struct {
+	mu       sync.Mutex
+	draining bool
+	inFlight int
+	idle     chan struct{}
+}
+
+// begin registers a new creation, or returns false if the service is draining.
+// Every successful call to begin must be followed by a call to end.
+func (t *creationTracker) begin() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.draining {
+		return false
+	}
+
+	t.inFlight++
+
+	return true
+}
+
+// end marks a creation registered by begin as finished.
+func (t *creationTracker) end() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.inFlight--
+
+	if t.inFlight == 0 && t.idle != nil {
+		close(t.idle)
+		t.idle = nil
+	}
+}
+
+// isDraining reports whether drain has been called.
+func (t *creationTracker) isDraining() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.draining
+}
+
+// drain stops new creations from beginning and waits until those in flight have finished.
+//
+// If the context is done first, drain returns the number of creations still in flight
+// along with the context error.
+func (t *creationTracker) drain(ctx context.Context) (int, error) {
+	t.mu.Lock()
+
+	t.draining = true
+
+	if t.inFlight == 0 {
+		t.mu.Unlock()
+		return 0, nil
+	}
+
+	if t.idle == nil {
+		t.idle = make(chan struct{})
+	}
+
+	idle := t.idle
+
+	t.mu.Unlock()
+
+	select {
+	case <-idle:
+		return 0, nil
+	case <-ctx.Done():
+		t.mu.Lock()
+		defer t.mu.Unlock()
+
+		return t.inFlight, ctx.Err()
+	}
+}
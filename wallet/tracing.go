@@ -0,0 +1,85 @@
+package wallet
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel/api/global"
+	"go.opentelemetry.io/otel/api/propagation"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/label"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/onflow/flow-account-api/pkg/tracing"
+)
+
+// traceHandler starts a span for each request, continuing any trace propagated by the caller.
+func traceHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := routeTemplate(r, r.URL.Path)
+
+		ctx := propagation.ExtractHTTP(r.Context(), global.Propagators(), r.Header)
+
+		ctx, span := tracing.StartSpan(
+			ctx,
+			r.Method+" "+route,
+			label.String("http.method", r.Method),
+			label.String("http.route", route),
+		)
+		defer span.End()
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		span.SetAttributes(label.Int("http.status_code", recorder.status))
+
+		if recorder.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Internal, http.StatusText(recorder.status))
+		}
+	})
+}
+
+// traceUnaryInterceptor starts a span for each gRPC call, continuing any trace propagated by the caller.
+func traceUnaryInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	ctx = propagation.ExtractHTTP(ctx, global.Propagators(), metadataSupplier{md})
+
+	ctx, span := tracing.StartSpan(ctx, info.FullMethod, label.String("rpc.method", info.FullMethod))
+	defer span.End()
+
+	res, err := handler(ctx, req)
+	if err != nil {
+		s := status.Convert(err)
+		span.SetStatus(codes.Code(s.Code()), s.Message())
+	}
+
+	return res, err
+}
+
+// metadataSupplier reads and writes propagated trace context in gRPC metadata,
+// as the propagators do in HTTP headers.
+type metadataSupplier struct {
+	md metadata.MD
+}
+
+func (s metadataSupplier) Get(key string) string {
+	values := s.md.Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
+
+func (s metadataSupplier) Set(key, value string) {
+	s.md.Set(key, value)
+}
@@ -0,0 +1,99 @@
+package wallet
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/api/global"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/onflow/flow-account-api/pkg/tracing"
+)
+
+const (
+	testTraceID     = "4bf92f3577b34da6a3ce929d0e0e4736"
+	testTraceparent = "00-" + testTraceID + "-00f067aa0ba902b7-01"
+)
+
+// installTestTraceProvider makes spans record their trace IDs, which the default no-op provider does not.
+func installTestTraceProvider(t *testing.T) {
+	t.Helper()
+
+	provider, err := sdktrace.NewProvider(
+		sdktrace.WithConfig(sdktrace.Config{DefaultSampler: sdktrace.AlwaysSample()}),
+	)
+	if err != nil {
+		t.Fatalf("failed to create trace provider: %v", err)
+	}
+
+	global.SetTraceProvider(provider)
+}
+
+// TestTracePropagation checks that the HTTP and gRPC APIs both continue traces propagated by callers.
+func TestTracePropagation(t *testing.T) {
+	installTestTraceProvider(t)
+
+	tests := []struct {
+		name        string
+		traceparent string
+		want        string
+	}{
+		{name: "Propagated", traceparent: testTraceparent, want: testTraceID},
+		{name: "NotPropagated"},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			var httpTraceID string
+
+			handler := traceHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				httpTraceID = tracing.TraceID(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/accounts", nil)
+			if test.traceparent != "" {
+				req.Header.Set("traceparent", test.traceparent)
+			}
+
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			var grpcTraceID string
+
+			ctx := context.Background()
+			if test.traceparent != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("traceparent", test.traceparent))
+			}
+
+			_, err := traceUnaryInterceptor(
+				ctx,
+				nil,
+				&grpc.UnaryServerInfo{FullMethod: "/flow.accountapi.AccountService/GetAccountByAddress"},
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					grpcTraceID = tracing.TraceID(ctx)
+					return nil, nil
+				},
+			)
+			if err != nil {
+				t.Fatalf("interceptor failed: %v", err)
+			}
+
+			if test.want != "" {
+				if httpTraceID != test.want || grpcTraceID != test.want {
+					t.Errorf("got HTTP trace %q and gRPC trace %q, want both to continue %q", httpTraceID, grpcTraceID, test.want)
+				}
+
+				return
+			}
+
+			if httpTraceID == "" || grpcTraceID == "" {
+				t.Errorf("got HTTP trace %q and gRPC trace %q, want both to start a new trace", httpTraceID, grpcTraceID)
+			}
+		})
+	}
+}
@@ -0,0 +1,445 @@
+package wallet
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/onflow/flow-account-api/model"
+	"github.com/onflow/flow-account-api/storage"
+)
+
+// Headers sent with each webhook callback.
+const (
+	webhookIDHeader        = "X-Webhook-ID"
+	webhookEventHeader     = "X-Webhook-Event"
+	webhookTimestampHeader = "X-Webhook-Timestamp"
+	webhookSignatureHeader = "X-Webhook-Signature"
+)
+
+// webhookBatchSize is the number of deliveries a dispatcher claims and sends concurrently.
+const webhookBatchSize = 20
+
+// Outcomes of delivery attempts, used to label the delivery counter.
+const (
+	webhookOutcomeDelivered = "delivered"
+	webhookOutcomeRetry     = "retry"
+	webhookOutcomeDead      = "dead"
+)
+
+var errUnknownSubscription = errors.New("subscription is no longer configured")
+
+// WebhookSubscription is an endpoint that is notified of account lifecycle events.
+type WebhookSubscription struct {
+	Name   string   `json:"name"`
+	URL    string   `json:"url"`
+	Secret string   `json:"secret"` // key of the HMAC-SHA256 signature sent with each callback
+	Events []string `json:"events"` // event types to send; empty means every type
+}
+
+func (sub WebhookSubscription) wants(eventType string) bool {
+	if len(sub.Events) == 0 {
+		return true
+	}
+
+	for _, e := range sub.Events {
+		if e == eventType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ReadWebhookSubscriptions decodes a JSON array of webhook subscriptions and checks that they are valid.
+func ReadWebhookSubscriptions(r io.Reader) ([]WebhookSubscription, error) {
+	var subscriptions []WebhookSubscription
+
+	err := json.NewDecoder(r).Decode(&subscriptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode webhook subscriptions: %w", err)
+	}
+
+	names := make(map[string]bool, len(subscriptions))
+
+	for _, sub := range subscriptions {
+		if sub.Name == "" {
+			return nil, errors.New("webhook subscription name must not be empty")
+		}
+
+		if names[sub.Name] {
+			return nil, fmt.Errorf("duplicate webhook subscription %q", sub.Name)
+		}
+
+		names[sub.Name] = true
+
+		u, err := url.Parse(sub.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("webhook subscription %q must have an http or https URL", sub.Name)
+		}
+
+		if sub.Secret == "" {
+			return nil, fmt.Errorf("webhook subscription %q must have a secret", sub.Name)
+		}
+
+		for _, e := range sub.Events {
+			if e != model.WebhookEventAccountCreated && e != model.WebhookEventAccountCreationFailed {
+				return nil, fmt.Errorf("webhook subscription %q has unknown event type %q", sub.Name, e)
+			}
+		}
+	}
+
+	return subscriptions, nil
+}
+
+// webhookEvent is the JSON body of a webhook callback.
+type webhookEvent struct {
+	ID        string           `json:"id"`
+	Type      string           `json:"type"`
+	CreatedAt time.Time        `json:"createdAt"`
+	Data      webhookEventData `json:"data"`
+}
+
+type webhookEventData struct {
+	Address         string   `json:"address,omitempty"`
+	PublicKeys      []string `json:"publicKeys,omitempty"`
+	TransactionID   string   `json:"transactionId,omitempty"`
+	ClaimedClientID string   `json:"claimedClientId,omitempty"`
+	RequestID       string   `json:"requestId,omitempty"`
+	Error           string   `json:"error,omitempty"`
+}
+
+// queueWebhooks adds a delivery of an account creation outcome to the outbox for each subscription that wants it.
+//
+// Deliveries that cannot be queued are never sent, so the error is logged with the event ID
+// for operators to notify subscribers by other means.
+func (s *Service) queueWebhooks(ctx context.Context, event *model.AuditEvent) {
+	var eventType string
+
+	switch event.Type {
+	case model.AuditEventAccountCreated:
+		eventType = model.WebhookEventAccountCreated
+	case model.AuditEventAccountCreationFailed:
+		eventType = model.WebhookEventAccountCreationFailed
+	default:
+		return
+	}
+
+	now := time.Now().UTC()
+	eventID := newRandomID()
+
+	payload, err := json.Marshal(&webhookEvent{
+		ID:        eventID,
+		Type:      eventType,
+		CreatedAt: now,
+		Data: webhookEventData{
+			Address:         event.Address,
+			PublicKeys:      event.PublicKeys,
+			TransactionID:   event.TransactionID,
+			ClaimedClientID: event.ClaimedClientID,
+			RequestID:       event.RequestID,
+			Error:           event.Error,
+		},
+	})
+	if err != nil {
+		s.loggerFor(ctx).Error().Err(err).Msg("failed to encode webhook event")
+		return
+	}
+
+	var deliveries []*model.WebhookDelivery
+
+	for _, sub := range s.webhooks {
+		if !sub.wants(eventType) {
+			continue
+		}
+
+		deliveries = append(deliveries, &model.WebhookDelivery{
+			EventID:       eventID,
+			EventType:     eventType,
+			Subscription:  sub.Name,
+			Payload:       string(payload),
+			Status:        model.WebhookDeliveryPending,
+			NextAttemptAt: now,
+		})
+	}
+
+	if len(deliveries) == 0 {
+		return
+	}
+
+	err = s.store.InsertWebhookDeliveries(ctx, deliveries)
+	if err != nil {
+		s.loggerFor(ctx).Error().
+			Err(err).
+			Str("eventId", eventID).
+			Str("eventType", eventType).
+			Str("address", event.Address).
+			Msg("failed to queue webhook deliveries")
+	}
+}
+
+func (s *Service) listDeadWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit, err := parseListLimit(query)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	afterID, err := storage.DecodeIDCursor(query.Get("cursor"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid cursor")
+		return
+	}
+
+	page, err := s.store.ListWebhookDeliveries(r.Context(), model.WebhookDeliveryDead, afterID, limit)
+	if err != nil {
+		s.loggerFor(r.Context()).Error().Err(err).Msg("failed to list dead webhook deliveries")
+
+		respondWithError(
+			w,
+			http.StatusInternalServerError,
+			"failed to list dead webhook deliveries",
+		)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, page)
+}
+
+// WebhookConfig configures the delivery of webhook callbacks.
+type WebhookConfig struct {
+	Subscriptions []WebhookSubscription
+	Timeout       time.Duration // bounds each callback
+	MaxAttempts   int           // attempts before a delivery is moved to the dead letters
+	RetryDelay    time.Duration // delay before the first retry, doubled for each further retry
+	MaxRetryDelay time.Duration
+	PollInterval  time.Duration // how often the outbox is checked for due deliveries
+}
+
+// WebhookDispatcher is a routine that sends the deliveries queued in the outbox,
+// retrying failed deliveries with exponential backoff.
+//
+// Deliveries are sent at least once: a delivery that was sent but not marked as delivered,
+// for example because the process stopped, is sent again once its claim expires.
+type WebhookDispatcher struct {
+	store         storage.Store
+	conf          WebhookConfig
+	subscriptions map[string]WebhookSubscription
+	client        *http.Client
+	metrics       *AccountsCollector
+	logger        zerolog.Logger
+	ctx           context.Context
+	cancel        context.CancelFunc
+}
+
+func NewWebhookDispatcher(
+	store storage.Store,
+	conf WebhookConfig,
+	metrics *AccountsCollector,
+	logger zerolog.Logger,
+) *WebhookDispatcher {
+	subscriptions := make(map[string]WebhookSubscription, len(conf.Subscriptions))
+	for _, sub := range conf.Subscriptions {
+		subscriptions[sub.Name] = sub
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &WebhookDispatcher{
+		store:         newInstrumentedStore(store, metrics),
+		conf:          conf,
+		subscriptions: subscriptions,
+		client:        &http.Client{Timeout: conf.Timeout},
+		metrics:       metrics,
+		logger:        logger,
+		ctx:           ctx,
+		cancel:        cancel,
+	}
+}
+
+// Start sends due deliveries until the dispatcher is stopped.
+func (d *WebhookDispatcher) Start() error {
+	ticker := time.NewTicker(d.conf.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		d.dispatch()
+
+		select {
+		case <-ticker.C:
+		case <-d.ctx.Done():
+			return nil
+		}
+	}
+}
+
+// Stop stops the dispatcher, abandoning any callbacks in flight.
+// Abandoned deliveries are sent again once their claim expires.
+func (d *WebhookDispatcher) Stop() {
+	d.cancel()
+}
+
+func (d *WebhookDispatcher) dispatch() {
+	// a claim outlasts the callbacks of a batch, which are sent concurrently, and the updates that follow them
+	lease := 2 * d.conf.Timeout
+
+	deliveries, err := d.store.ClaimWebhookDeliveries(d.ctx, time.Now().UTC(), lease, webhookBatchSize)
+	if err != nil {
+		if d.ctx.Err() == nil {
+			d.logger.Warn().Err(err).Msg("failed to claim webhook deliveries")
+		}
+
+		return
+	}
+
+	var wg sync.WaitGroup
+
+	for _, delivery := range deliveries {
+		wg.Add(1)
+
+		go func(delivery *model.WebhookDelivery) {
+			defer wg.Done()
+			d.deliver(delivery)
+		}(delivery)
+	}
+
+	wg.Wait()
+}
+
+func (d *WebhookDispatcher) deliver(delivery *model.WebhookDelivery) {
+	logger := d.logger.With().
+		Int64("deliveryId", delivery.ID).
+		Str("eventId", delivery.EventID).
+		Str("subscription", delivery.Subscription).
+		Logger()
+
+	sub, ok := d.subscriptions[delivery.Subscription]
+
+	err := errUnknownSubscription
+	if ok {
+		err = d.send(sub, delivery)
+	}
+
+	if d.ctx.Err() != nil {
+		// stopped while sending, so leave the delivery to be sent again
+		return
+	}
+
+	now := time.Now().UTC()
+
+	delivery.Attempts++
+	delivery.LastAttemptAt = now
+
+	var outcome string
+
+	switch {
+	case err == nil:
+		outcome = webhookOutcomeDelivered
+		delivery.Status = model.WebhookDeliveryDelivered
+		delivery.LastError = ""
+	case !ok || delivery.Attempts >= d.conf.MaxAttempts:
+		outcome = webhookOutcomeDead
+		delivery.Status = model.WebhookDeliveryDead
+		delivery.LastError = err.Error()
+	default:
+		outcome = webhookOutcomeRetry
+		delivery.NextAttemptAt = now.Add(d.retryDelay(delivery.Attempts))
+		delivery.LastError = err.Error()
+	}
+
+	d.metrics.webhookDeliveryAttempted(delivery.Subscription, outcome)
+
+	updateErr := d.store.UpdateWebhookDelivery(d.ctx, delivery)
+	if updateErr != nil {
+		logger.Error().Err(updateErr).Msg("failed to update webhook delivery")
+		return
+	}
+
+	switch outcome {
+	case webhookOutcomeDead:
+		logger.Error().Err(err).Int("attempts", delivery.Attempts).Msg("giving up on webhook delivery")
+	case webhookOutcomeRetry:
+		logger.Warn().
+			Err(err).
+			Int("attempts", delivery.Attempts).
+			Time("nextAttemptAt", delivery.NextAttemptAt).
+			Msg("webhook delivery failed")
+	default:
+		logger.Debug().Int("attempts", delivery.Attempts).Msg("delivered webhook")
+	}
+}
+
+// retryDelay returns the delay before the attempt following the given number of failed attempts.
+func (d *WebhookDispatcher) retryDelay(attempts int) time.Duration {
+	delay := d.conf.RetryDelay
+
+	for i := 1; i < attempts && delay < d.conf.MaxRetryDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > d.conf.MaxRetryDelay {
+		delay = d.conf.MaxRetryDelay
+	}
+
+	return delay
+}
+
+// send posts a delivery to its subscription, signed with the subscription secret.
+func (d *WebhookDispatcher) send(sub WebhookSubscription, delivery *model.WebhookDelivery) error {
+	body := []byte(delivery.Payload)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(d.ctx, http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhookIDHeader, delivery.EventID)
+	req.Header.Set(webhookEventHeader, delivery.EventType)
+	req.Header.Set(webhookTimestampHeader, timestamp)
+	req.Header.Set(webhookSignatureHeader, "sha256="+signWebhook(sub.Secret, timestamp, body))
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	// drain the body so that the connection can be reused
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(res.Body, 64*1024))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("subscriber responded with status %d", res.StatusCode)
+	}
+
+	return nil
+}
+
+// signWebhook returns the hex-encoded HMAC-SHA256 of the timestamp and body of a callback,
+// joined by a period, so that subscribers can reject replayed callbacks.
+func signWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,355 @@
+package wallet
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/onflow/flow-account-api/model"
+	"github.com/onflow/flow-account-api/storage"
+	"github.com/onflow/flow-account-api/storage/memory"
+)
+
+// listDeliveries returns the deliveries in the store with the given status.
+func listDeliveries(t *testing.T, store storage.Store, status string) []*model.WebhookDelivery {
+	t.Helper()
+
+	page, err := store.ListWebhookDeliveries(context.Background(), status, 0, 100)
+	if err != nil {
+		t.Fatalf("failed to list %s webhook deliveries: %v", status, err)
+	}
+
+	return page.Deliveries
+}
+
+func TestRejectedCreationsAreNotSentToWebhooks(t *testing.T) {
+	publicKey := testPublicKey(t)
+
+	existing := &model.Account{
+		Address: "0000000000000001",
+		PublicKeys: []*model.AccountPublicKey{
+			{PublicKey: publicKey, SigAlgo: "ECDSA_P256", HashAlgo: "SHA3_256"},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		accountLimit int
+		accounts     []*model.Account
+		publicKey    string
+		hashAlgo     string
+		wantErr      error
+	}{
+		{name: "InvalidKey", publicKey: publicKey[:64], hashAlgo: "SHA3_256", wantErr: errInvalidPublicKey},
+		{name: "DisallowedAlgorithms", publicKey: publicKey, hashAlgo: "SHA2_384", wantErr: errInvalidHashAlgo},
+		{
+			name:         "AccountLimitReached",
+			accountLimit: 1,
+			accounts:     []*model.Account{existing},
+			publicKey:    publicKey,
+			hashAlgo:     "SHA3_256",
+			wantErr:      ErrAccountLimitExceeded,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			store := memory.NewStore()
+
+			for _, account := range copyAccounts(test.accounts) {
+				if err := store.InsertAccount(context.Background(), account, nil); err != nil {
+					t.Fatalf("failed to insert account: %v", err)
+				}
+			}
+
+			s := NewService(
+				ServerConfig{},
+				zerolog.Nop(),
+				&Accounts{accountLimit: test.accountLimit, network: "emulator", metrics: testMetrics, logger: zerolog.Nop()},
+				store,
+				testMetrics,
+				[]WebhookSubscription{{Name: "backend", URL: "http://localhost", Secret: "secret"}},
+				time.Second,
+				false,
+			)
+
+			_, err := s.CreateAccount(context.Background(), test.publicKey, "ECDSA_P256", test.hashAlgo, model.ClientMetadata{})
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("got error %v, want %v", err, test.wantErr)
+			}
+
+			page, err := store.ListAuditEvents(context.Background(), storage.AuditEventFilter{}, 0, 100)
+			if err != nil {
+				t.Fatalf("failed to list audit events: %v", err)
+			}
+
+			if len(page.Events) != 1 || page.Events[0].Type != model.AuditEventAccountCreationFailed {
+				t.Errorf("got audit events %+v, want the rejection to be recorded", page.Events)
+			}
+
+			if deliveries := listDeliveries(t, store, model.WebhookDeliveryPending); len(deliveries) != 0 {
+				t.Errorf("queued %d webhook deliveries for a rejected request, want none", len(deliveries))
+			}
+		})
+	}
+}
+
+func TestFailedCreationsAreSentToWebhooks(t *testing.T) {
+	store := memory.NewStore()
+
+	s := NewService(
+		ServerConfig{},
+		zerolog.Nop(),
+		&Accounts{network: "emulator", metrics: testMetrics, logger: zerolog.Nop()},
+		store,
+		testMetrics,
+		[]WebhookSubscription{
+			{Name: "all", URL: "http://localhost/all", Secret: "secret"},
+			{Name: "failures", URL: "http://localhost/failures", Secret: "secret", Events: []string{model.WebhookEventAccountCreationFailed}},
+			{Name: "created", URL: "http://localhost/created", Secret: "secret", Events: []string{model.WebhookEventAccountCreated}},
+		},
+		time.Second,
+		false,
+	)
+
+	s.recordFailedCreation(context.Background(), model.ClientMetadata{}, testPublicKey(t), nil, errors.New("transaction failed"))
+
+	deliveries := listDeliveries(t, store, model.WebhookDeliveryPending)
+
+	var subscriptions []string
+	for _, delivery := range deliveries {
+		subscriptions = append(subscriptions, delivery.Subscription)
+
+		if delivery.EventType != model.WebhookEventAccountCreationFailed {
+			t.Errorf("queued a %s delivery, want %s", delivery.EventType, model.WebhookEventAccountCreationFailed)
+		}
+	}
+
+	if len(subscriptions) != 2 || subscriptions[0] != "all" || subscriptions[1] != "failures" {
+		t.Errorf("queued deliveries for %v, want [all failures]", subscriptions)
+	}
+}
+
+// newTestDispatcher returns a dispatcher for a single subscription to url, with an in-memory outbox
+// holding the given delivery.
+func newTestDispatcher(t *testing.T, url string, conf WebhookConfig, delivery *model.WebhookDelivery) (*WebhookDispatcher, storage.Store) {
+	t.Helper()
+
+	store := memory.NewStore()
+
+	err := store.InsertWebhookDeliveries(context.Background(), []*model.WebhookDelivery{delivery})
+	if err != nil {
+		t.Fatalf("failed to queue webhook delivery: %v", err)
+	}
+
+	if conf.Subscriptions == nil {
+		conf.Subscriptions = []WebhookSubscription{{Name: "backend", URL: url, Secret: "secret"}}
+	}
+
+	conf.Timeout = 5 * time.Second
+
+	return NewWebhookDispatcher(store, conf, testMetrics, zerolog.Nop()), store
+}
+
+func newTestDelivery(attempts int) *model.WebhookDelivery {
+	return &model.WebhookDelivery{
+		EventID:       "18cd503069f9e83b974fa983e9a65587",
+		EventType:     model.WebhookEventAccountCreated,
+		Subscription:  "backend",
+		Payload:       `{"id":"18cd503069f9e83b974fa983e9a65587","type":"account.created"}`,
+		Status:        model.WebhookDeliveryPending,
+		Attempts:      attempts,
+		NextAttemptAt: time.Now().UTC().Add(-time.Second),
+	}
+}
+
+func TestWebhookDispatcherSignsCallbacks(t *testing.T) {
+	delivery := newTestDelivery(0)
+
+	var req *http.Request
+	var body []byte
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req = r
+		body, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	d, store := newTestDispatcher(t, server.URL, WebhookConfig{MaxAttempts: 3}, delivery)
+
+	before := time.Now().Unix()
+	d.dispatch()
+
+	if req == nil {
+		t.Fatal("dispatcher did not send the callback")
+	}
+
+	if string(body) != delivery.Payload {
+		t.Errorf("got body %q, want the queued payload %q", body, delivery.Payload)
+	}
+
+	timestamp := req.Header.Get(webhookTimestampHeader)
+
+	sent, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || sent < before || sent > time.Now().Unix() {
+		t.Errorf("got timestamp %q, want the Unix time the callback was sent", timestamp)
+	}
+
+	// the signature covers the timestamp, so that a captured callback cannot be replayed later
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(timestamp + "." + delivery.Payload))
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	if got := req.Header.Get(webhookSignatureHeader); got != want {
+		t.Errorf("got signature %q, want %q", got, want)
+	}
+
+	if got := req.Header.Get(webhookIDHeader); got != delivery.EventID {
+		t.Errorf("got event ID %q, want %q", got, delivery.EventID)
+	}
+
+	if got := req.Header.Get(webhookEventHeader); got != delivery.EventType {
+		t.Errorf("got event type %q, want %q", got, delivery.EventType)
+	}
+
+	delivered := listDeliveries(t, store, model.WebhookDeliveryDelivered)
+	if len(delivered) != 1 || delivered[0].Attempts != 1 {
+		t.Errorf("got delivered deliveries %+v, want the delivery marked delivered after one attempt", delivered)
+	}
+}
+
+func TestWebhookDispatcherRetriesWithBackoff(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	conf := WebhookConfig{MaxAttempts: 5, RetryDelay: time.Minute, MaxRetryDelay: 10 * time.Minute}
+
+	d, store := newTestDispatcher(t, server.URL, conf, newTestDelivery(2))
+
+	before := time.Now().UTC()
+	d.dispatch()
+
+	pending := listDeliveries(t, store, model.WebhookDeliveryPending)
+	if len(pending) != 1 {
+		t.Fatalf("got %d pending deliveries, want the failed delivery to stay pending", len(pending))
+	}
+
+	delivery := pending[0]
+
+	if delivery.Attempts != 3 {
+		t.Errorf("got %d attempts, want 3", delivery.Attempts)
+	}
+
+	if delivery.LastError != "subscriber responded with status 503" {
+		t.Errorf("got last error %q, want the subscriber status", delivery.LastError)
+	}
+
+	// the third failed attempt waits twice the doubled retry delay
+	if wait := delivery.NextAttemptAt.Sub(before); wait < 4*time.Minute || wait > 4*time.Minute+time.Minute/2 {
+		t.Errorf("next attempt is in %s, want 4m", wait)
+	}
+
+	// the delivery is not claimed again before its next attempt
+	d.dispatch()
+
+	if pending := listDeliveries(t, store, model.WebhookDeliveryPending); pending[0].Attempts != 3 {
+		t.Errorf("got %d attempts, want the delivery to wait for its next attempt", pending[0].Attempts)
+	}
+}
+
+func TestWebhookRetryDelay(t *testing.T) {
+	d := &WebhookDispatcher{conf: WebhookConfig{RetryDelay: time.Second, MaxRetryDelay: 10 * time.Second}}
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 1, want: time.Second},
+		{attempts: 2, want: 2 * time.Second},
+		{attempts: 3, want: 4 * time.Second},
+		{attempts: 4, want: 8 * time.Second},
+		{attempts: 5, want: 10 * time.Second},
+		{attempts: 100, want: 10 * time.Second},
+	}
+
+	for _, test := range tests {
+		if got := d.retryDelay(test.attempts); got != test.want {
+			t.Errorf("got a delay of %s after %d attempts, want %s", got, test.attempts, test.want)
+		}
+	}
+}
+
+func TestWebhookDispatcherDeadLetters(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name          string
+		subscriptions []WebhookSubscription
+		attempts      int
+		wantAttempts  int
+		wantError     string
+	}{
+		{
+			name:         "MaxAttemptsReached",
+			attempts:     2,
+			wantAttempts: 3,
+			wantError:    "subscriber responded with status 500",
+		},
+		{
+			name:          "UnknownSubscription",
+			subscriptions: []WebhookSubscription{{Name: "other", URL: server.URL, Secret: "secret"}},
+			wantAttempts:  1,
+			wantError:     errUnknownSubscription.Error(),
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			conf := WebhookConfig{
+				Subscriptions: test.subscriptions,
+				MaxAttempts:   3,
+				RetryDelay:    time.Minute,
+				MaxRetryDelay: time.Hour,
+			}
+
+			d, store := newTestDispatcher(t, server.URL, conf, newTestDelivery(test.attempts))
+
+			d.dispatch()
+
+			if pending := listDeliveries(t, store, model.WebhookDeliveryPending); len(pending) != 0 {
+				t.Errorf("got %d pending deliveries, want none", len(pending))
+			}
+
+			dead := listDeliveries(t, store, model.WebhookDeliveryDead)
+			if len(dead) != 1 {
+				t.Fatalf("got %d dead deliveries, want 1", len(dead))
+			}
+
+			if dead[0].Attempts != test.wantAttempts || dead[0].LastError != test.wantError {
+				t.Errorf(
+					"got a dead delivery after %d attempts with error %q, want %d attempts with %q",
+					dead[0].Attempts, dead[0].LastError, test.wantAttempts, test.wantError,
+				)
+			}
+		})
+	}
+}